@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+)
+
+//go:embed templates
+var templates embed.FS
+
+const monthFormat = "2006-01"
+
+// calendarDay is a cell in the archive calendar. A zero Day is padding outside of the month.
+type calendarDay struct {
+	Day    int
+	Issues []*Issue
+	Latest bool
+	Today  bool
+}
+
+type archivePage struct {
+	Month, PrevMonth, NextMonth time.Time
+	Prev, Next                  string
+	Weekdays                    []string
+	Weeks                       [][]calendarDay
+	Latest                      *Issue
+}
+
+// archiveHandler renders a month calendar linking to every stored issue.
+//
+// The month is selected with the `month` param in YYYY-MM format, defaulting to the
+// month of the latest issue.
+func archiveHandler(store Storage) (http.HandlerFunc, error) {
+	tmpl, err := template.ParseFS(templates, "templates/archive.html")
+	if err != nil {
+		return nil, err
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		newest := latest.Load()
+		today := issueToday()
+
+		month := today
+		if newest != nil {
+			month = newest.Date
+		}
+		if v := r.URL.Query().Get("month"); v != "" {
+			var err error
+			if month, err = parseIssueDate(monthFormat, v); err != nil {
+				handleHTTPError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		month = time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, issueLocation)
+
+		byDay := make(map[int][]*Issue)
+		for issue, err := range listIssues(r.Context(), store, month.Format("2006/01/")) {
+			if err != nil {
+				handleStorageError(w, err)
+				return
+			}
+			byDay[issue.Date.Day()] = append(byDay[issue.Date.Day()], issue.Issue)
+		}
+
+		page := archivePage{
+			Month:     month,
+			PrevMonth: month.AddDate(0, -1, 0),
+			NextMonth: month.AddDate(0, 1, 0),
+			Weekdays:  make([]string, 0, 7),
+			Latest:    newest,
+		}
+		page.Prev = "/archive?month=" + page.PrevMonth.Format(monthFormat)
+		if !page.NextMonth.After(today) {
+			page.Next = "/archive?month=" + page.NextMonth.Format(monthFormat)
+		}
+		for d := range 7 {
+			page.Weekdays = append(page.Weekdays, time.Weekday(d).String()[:3])
+		}
+
+		week := make([]calendarDay, int(month.Weekday()), 7)
+		for d := month; d.Month() == month.Month(); d = d.AddDate(0, 0, 1) {
+			issues := byDay[d.Day()]
+			// Show the default format first so the day links to it.
+			slices.SortStableFunc(issues, func(a, b *Issue) int {
+				return boolCmp(b.Ext == defaultExt, a.Ext == defaultExt)
+			})
+
+			week = append(week, calendarDay{
+				Day:    d.Day(),
+				Issues: issues,
+				Latest: newest != nil && d.Equal(newest.Date),
+				Today:  d.Equal(today),
+			})
+			if len(week) == 7 {
+				page.Weeks = append(page.Weeks, week)
+				week = make([]calendarDay, 0, 7)
+			}
+		}
+		if len(week) != 0 {
+			page.Weeks = append(page.Weeks, append(week, make([]calendarDay, 7-len(week))...))
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, page); err != nil {
+			slog.Error("Failed to render archive", "error", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = buf.WriteTo(w)
+	}, nil
+}
+
+// boolCmp orders false before true.
+func boolCmp(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
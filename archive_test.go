@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveHandler(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+	latest.Store(NewIssueFromDate(time.Date(2026, 8, 5, 0, 0, 0, 0, time.UTC), ".pdf"))
+
+	store := newMemStorage("2026/07/31.pdf", "2026/08/04.pdf", "2026/08/05.epub", "2026/08/05.pdf")
+	handler, err := archiveHandler(store)
+	require.NoError(t, err)
+
+	get := func(t *testing.T, target string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequestWithContext(t.Context(), http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	t.Run("defaults to latest month", func(t *testing.T) {
+		w := get(t, "/archive")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+
+		body := w.Body.String()
+		assert.Contains(t, body, "<h1>August 2026</h1>")
+		assert.Contains(t, body, `href="/2026-08-04.pdf"`)
+		assert.Contains(t, body, `<a href="/2026-08-05.pdf">5</a>`, "day should link to the pdf")
+		assert.Contains(t, body, `href="/2026-08-05.epub"`)
+		assert.NotContains(t, body, "2026-07-31", "other months should be excluded")
+		assert.Contains(t, body, `href="/archive?month=2026-07"`)
+		assert.Equal(t, 1, strings.Count(body, `class="issue latest`))
+	})
+
+	t.Run("month param", func(t *testing.T) {
+		w := get(t, "/archive?month=2026-07")
+		require.Equal(t, http.StatusOK, w.Code)
+		body := w.Body.String()
+		assert.Contains(t, body, "<h1>July 2026</h1>")
+		assert.Contains(t, body, `href="/2026-07-31.pdf"`)
+		assert.Contains(t, body, `href="/archive?month=2026-08"`)
+		assert.NotContains(t, body, `class="issue latest`)
+	})
+
+	t.Run("invalid month", func(t *testing.T) {
+		w := get(t, "/archive?month=August")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
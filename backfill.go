@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// maxBackfillDays limits the range of a single backfill request.
+const maxBackfillDays = 366
+
+type BackfillStatus string
+
+const (
+	// BackfillStored means the issue was fetched and stored.
+	BackfillStored BackfillStatus = "stored"
+	// BackfillExists means the issue was already stored, so it wasn't fetched.
+	BackfillExists BackfillStatus = "exists"
+	// BackfillSkipped means the publication calendar has no issue for the date.
+	BackfillSkipped BackfillStatus = "skipped"
+	// BackfillFailed means the fetch failed.
+	BackfillFailed BackfillStatus = "failed"
+)
+
+var ErrInvalidBackfill = errors.New("invalid backfill")
+
+// BackfillResult is the outcome of backfilling a single date.
+type BackfillResult struct {
+	Date   string         `json:"date"`
+	Status BackfillStatus `json:"status"`
+	URL    string         `json:"url,omitempty"`
+	Issue  string         `json:"issue,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// backfillURLData is passed to the `url` template of a backfill request.
+type backfillURLData struct {
+	Date time.Time
+}
+
+// backfillItem is a date to backfill and the URL rendered for it.
+type backfillItem struct {
+	date time.Time
+	url  *url.URL
+}
+
+// backfillHandler fetches the issue for every date from the `from` param to the `to` param,
+// in YYYY-MM-DD format and inclusive, and responds with a result for each date.
+//
+// The `url` param is a text/template rendered for each date, like
+// `https://example.com/issue-{{.Date.Format "1-2-2006"}}.pdf`. Issues are stored under the
+// date they were requested for, and fail if the upstream filename has a different date.
+// Dates that are already stored or that calendar has no issue for are skipped, and the rest
+// are fetched conf.BackfillConcurrency at a time.
+func backfillHandler(conf *Config, fetcher *Fetcher, calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(conf, r) {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		items, err := parseBackfillForm(r)
+		if err != nil {
+			handleHTTPError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		results := make([]BackfillResult, len(items))
+		sem := make(chan struct{}, max(conf.BackfillConcurrency, 1))
+		var wg sync.WaitGroup
+		for i, item := range items {
+			results[i] = BackfillResult{Date: item.date.Format(time.DateOnly), URL: item.url.String()}
+			if !calendar.Publishes(item.date) {
+				results[i].Status = BackfillSkipped
+				continue
+			}
+
+			wg.Go(func() {
+				select {
+				case sem <- struct{}{}:
+					defer func() { <-sem }()
+				case <-r.Context().Done():
+					results[i].Status = BackfillFailed
+					results[i].Error = r.Context().Err().Error()
+					return
+				}
+				backfillDate(r.Context(), fetcher, item, &results[i])
+			})
+		}
+		wg.Wait()
+
+		res := struct {
+			Summary map[BackfillStatus]int `json:"summary"`
+			Results []BackfillResult       `json:"results"`
+		}{
+			Summary: make(map[BackfillStatus]int, 4),
+			Results: results,
+		}
+		for _, result := range results {
+			res.Summary[result.Status]++
+		}
+		slog.Info("Backfill finished", "from", results[0].Date, "to", results[len(results)-1].Date,
+			"stored", res.Summary[BackfillStored], "failed", res.Summary[BackfillFailed])
+
+		writeJSON(w, http.StatusOK, res)
+	}
+}
+
+// parseBackfillForm parses the `from`, `to` and `url` params into an item for every date in the range.
+func parseBackfillForm(r *http.Request) ([]backfillItem, error) {
+	var from, to time.Time
+	for _, param := range []struct {
+		name string
+		v    *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		v := r.FormValue(param.name)
+		if v == "" {
+			return nil, fmt.Errorf("%w: missing %s", ErrInvalidBackfill, param.name)
+		}
+		var err error
+		if *param.v, err = parseIssueDate(time.DateOnly, v); err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidBackfill, param.name, err)
+		}
+	}
+	if from.After(to) || to.Sub(from) > maxBackfillDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: from must not be after to, and at most %d days before it",
+			ErrInvalidBackfill, maxBackfillDays)
+	}
+
+	src := r.FormValue("url")
+	if src == "" {
+		return nil, fmt.Errorf("%w: missing url", ErrInvalidBackfill)
+	}
+	tmpl, err := template.New("url").Option("missingkey=error").Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("%w: url: %w", ErrInvalidBackfill, err)
+	}
+
+	// Render every URL up front so that a broken template fails before anything is fetched.
+	var items []backfillItem
+	var buf bytes.Buffer
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		buf.Reset()
+		if err := tmpl.Execute(&buf, backfillURLData{Date: date}); err != nil {
+			return nil, fmt.Errorf("%w: url: %w", ErrInvalidBackfill, err)
+		}
+		u, err := parseSourceURL(buf.String())
+		if err != nil {
+			return nil, fmt.Errorf("%w: url: %w", ErrInvalidBackfill, err)
+		}
+		items = append(items, backfillItem{date: date, url: u})
+	}
+	return items, nil
+}
+
+// backfillDate fetches the issue for item unless it is already stored, and records the outcome in result.
+func backfillDate(ctx context.Context, fetcher *Fetcher, item backfillItem, result *BackfillResult) {
+	// Fetch checks whether the date is already stored before downloading anything. Date is
+	// only used for filenames without a date, so a redirect to another issue fails.
+	issue, err := fetcher.Fetch(ctx, item.url, FetchOptions{Date: item.date, Expect: item.date})
+	switch {
+	case err == nil:
+		result.Status = BackfillStored
+		result.Issue = "/" + issue.ShortPath()
+	case errors.Is(err, ErrExists):
+		result.Status = BackfillExists
+		result.Issue = "/" + issue.ShortPath()
+	default:
+		slog.Warn("Backfill failed", "date", result.Date, "url", result.URL, "error", err)
+		result.Status = BackfillFailed
+		result.Error = err.Error()
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackfillHandler(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+
+	var active, peak atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			if p := peak.Load(); n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		switch {
+		case strings.Contains(r.URL.Path, "8-4-2026"):
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		case strings.Contains(r.URL.Path, "8-6-2026"):
+			// Missing issues redirect to the latest paper.
+			http.Redirect(w, r, "/files/a1b2-issue-8-5-2026.pdf", http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("%PDF-1.4 fake"))
+	}))
+	t.Cleanup(upstream.Close)
+
+	store := newMemStorage("2026/08/03.pdf")
+	conf := newTestConfig()
+	conf.BackfillConcurrency = 2
+	handler := backfillHandler(conf, NewFetcher(conf, store), newTestCalendar(t))
+
+	post := func(t *testing.T, form url.Values, auth bool) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/api/backfill", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if auth {
+			r.Header.Set("Authorization", authKey)
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	// The filenames have no random prefix, so dates can only come from the template.
+	form := url.Values{
+		"from": {"2026-08-01"},
+		"to":   {"2026-08-06"},
+		"url":  {upstream.URL + `/files/issue-{{.Date.Format "1-2-2006"}}.pdf`},
+	}
+
+	t.Run("unauthorized", func(t *testing.T) {
+		w := post(t, form, false)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("backfill", func(t *testing.T) {
+		w := post(t, form, true)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var res struct {
+			Summary map[BackfillStatus]int `json:"summary"`
+			Results []BackfillResult       `json:"results"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+
+		got := make(map[string]BackfillStatus, len(res.Results))
+		for _, result := range res.Results {
+			got[result.Date] = result.Status
+		}
+		assert.Equal(t, map[string]BackfillStatus{
+			"2026-08-01": BackfillStored,
+			"2026-08-02": BackfillSkipped, // Sunday
+			"2026-08-03": BackfillExists,
+			"2026-08-04": BackfillFailed,
+			"2026-08-05": BackfillStored,
+			"2026-08-06": BackfillFailed, // Redirects to 2026-08-05
+		}, got)
+		assert.Equal(t, map[BackfillStatus]int{
+			BackfillStored: 2, BackfillSkipped: 1, BackfillExists: 1, BackfillFailed: 2,
+		}, res.Summary)
+		assert.Equal(t, "2026-08-01", res.Results[0].Date, "results should be in date order")
+		assert.Equal(t, upstream.URL+"/files/issue-8-1-2026.pdf", res.Results[0].URL)
+		assert.Contains(t, res.Results[3].Error, "404")
+		assert.Contains(t, res.Results[5].Error, ErrNotPublished.Error())
+
+		assert.Equal(t, []string{"2026/08/01.pdf", "2026/08/03.pdf", "2026/08/05.pdf"}, store.Keys())
+		assert.LessOrEqual(t, peak.Load(), int32(2), "should limit concurrency")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		tests := []struct {
+			name string
+			set  map[string]string
+		}{
+			{"missing from", map[string]string{"from": ""}},
+			{"invalid to", map[string]string{"to": "tomorrow"}},
+			{"reversed", map[string]string{"from": "2026-08-06", "to": "2026-08-01"}},
+			{"too long", map[string]string{"from": "2024-01-01"}},
+			{"missing url", map[string]string{"url": ""}},
+			{"invalid template", map[string]string{"url": "https://example.com/{{.Date"}},
+			{"unknown field", map[string]string{"url": "https://example.com/{{.Day}}"}},
+			{"invalid scheme", map[string]string{"url": "ftp://example.com/{{.Date.Format \"1-2-2006\"}}"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				invalid := url.Values{}
+				for k, v := range form {
+					invalid[k] = v
+				}
+				for k, v := range tt.set {
+					invalid.Set(k, v)
+				}
+				w := post(t, invalid, true)
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+			})
+		}
+	})
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCalendar = errors.New("invalid publication calendar")
+
+// maxGapsDays limits the range checked by gapsHandler.
+const maxGapsDays = 366
+
+// NewCalendar returns the publication calendar configured by conf.PublicationDays,
+// conf.PublicationHolidays and conf.PublicationGracePeriod.
+func NewCalendar(conf *Config) (*Calendar, error) {
+	c := &Calendar{grace: conf.PublicationGracePeriod}
+
+	if len(conf.PublicationDays) != 0 {
+		c.closed = [7]bool{true, true, true, true, true, true, true}
+		for _, name := range conf.PublicationDays {
+			day, err := parseWeekday(name)
+			if err != nil {
+				return nil, err
+			}
+			c.closed[day] = false
+		}
+	}
+
+	if len(conf.PublicationHolidays) != 0 {
+		c.holidays = make(map[string]struct{}, len(conf.PublicationHolidays))
+		for _, v := range conf.PublicationHolidays {
+			date, err := time.Parse(time.DateOnly, strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("%w: holiday: %w", ErrInvalidCalendar, err)
+			}
+			c.holidays[date.Format(time.DateOnly)] = struct{}{}
+		}
+	}
+	return c, nil
+}
+
+// parseWeekday parses a weekday name like `monday` or `mon`, ignoring case.
+func parseWeekday(name string) (time.Weekday, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for d := range 7 {
+		day := strings.ToLower(time.Weekday(d).String())
+		if name == day || name == day[:3] {
+			return time.Weekday(d), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: unknown weekday %q", ErrInvalidCalendar, name)
+}
+
+// Calendar describes the dates the paper is published, so that days without a paper
+// can be told apart from failed fetches. The zero value publishes every day.
+type Calendar struct {
+	// closed marks weekdays without an issue.
+	closed   [7]bool
+	holidays map[string]struct{}
+	// grace is how long after midnight of a publication date its issue is expected to be stored.
+	grace time.Duration
+}
+
+// Publishes reports whether an issue is published on date.
+func (c *Calendar) Publishes(date time.Time) bool {
+	if c.closed[date.Weekday()] {
+		return false
+	}
+	_, holiday := c.holidays[date.Format(time.DateOnly)]
+	return !holiday
+}
+
+// Due reports whether the issue for date should be stored by now.
+func (c *Calendar) Due(date, now time.Time) bool {
+	return c.Publishes(date) && !date.Add(c.grace).After(now)
+}
+
+// Expected iterates over the publication dates from from to to, inclusive.
+func (c *Calendar) Expected(from, to time.Time) iter.Seq[time.Time] {
+	return func(yield func(time.Time) bool) {
+		for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+			if c.Publishes(d) && !yield(d) {
+				return
+			}
+		}
+	}
+}
+
+// LastDue returns the most recent publication date whose issue should be stored by now,
+// or the zero time if there is none within a year.
+func (c *Calendar) LastDue(now time.Time) time.Time {
+	now = now.In(issueLocation)
+	d := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, issueLocation)
+	for range 366 {
+		if c.Due(d, now) {
+			return d
+		}
+		d = d.AddDate(0, 0, -1)
+	}
+	return time.Time{}
+}
+
+// Overdue reports whether latest is older than the last issue that should be stored by now.
+// A missing issue on a day without a paper is not overdue.
+func (c *Calendar) Overdue(latest *Issue, now time.Time) bool {
+	due := c.LastDue(now)
+	if due.IsZero() {
+		return false
+	}
+	return latest == nil || latest.Date.Before(due)
+}
+
+// gapsHandler reports publication dates that are past their grace period without a stored issue.
+//
+// The range is selected with the `from` and `to` params in YYYY-MM-DD format, defaulting
+// to the last 30 days, and the format with `ext`, defaulting to PDF. The other params
+// accepted by ParseIssueFilter narrow the stored issues that are counted.
+func gapsHandler(store Storage, calendar *Calendar) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		filter, err := ParseIssueFilter(r.URL.Query())
+		if err != nil {
+			handleHTTPError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.To = cmp.Or(filter.To, issueToday())
+		filter.From = cmp.Or(filter.From, filter.To.AddDate(0, 0, -30))
+		filter.Ext = cmp.Or(filter.Ext, defaultExt)
+		if filter.From.After(filter.To) || filter.To.Sub(filter.From) > maxGapsDays*24*time.Hour {
+			handleHTTPError(w, fmt.Sprintf("%s: from must not be after to, and at most %d days before it",
+				ErrInvalidFilter, maxGapsDays), http.StatusBadRequest)
+			return
+		}
+
+		stored := make(map[string]struct{})
+		first := time.Date(filter.From.Year(), filter.From.Month(), 1, 0, 0, 0, 0, issueLocation)
+		for month := first; !month.After(filter.To); month = month.AddDate(0, 1, 0) {
+			for issue, err := range listIssues(r.Context(), store, month.Format("2006/01/")) {
+				if err != nil {
+					handleStorageError(w, err)
+					return
+				}
+				if filter.Match(issue.Issue) {
+					stored[issue.Date.Format(time.DateOnly)] = struct{}{}
+				}
+			}
+		}
+
+		res := struct {
+			From     string   `json:"from"`
+			To       string   `json:"to"`
+			Ext      string   `json:"ext"`
+			Expected int      `json:"expected"`
+			Stored   int      `json:"stored"`
+			Missing  []string `json:"missing"`
+			Latest   string   `json:"latest,omitempty"`
+			Overdue  bool     `json:"overdue"`
+		}{
+			From:    filter.From.Format(time.DateOnly),
+			To:      filter.To.Format(time.DateOnly),
+			Ext:     filter.Ext,
+			Missing: make([]string, 0),
+		}
+
+		for date := range calendar.Expected(filter.From, filter.To) {
+			if !calendar.Due(date, now) {
+				continue
+			}
+			res.Expected++
+			if _, ok := stored[date.Format(time.DateOnly)]; ok {
+				res.Stored++
+			} else {
+				res.Missing = append(res.Missing, date.Format(time.DateOnly))
+			}
+		}
+
+		issue := latest.Get(filter.Ext)
+		if issue != nil {
+			res.Latest = "/" + issue.ShortPath()
+		}
+		res.Overdue = calendar.Overdue(issue, now)
+
+		writeJSON(w, http.StatusOK, res)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCalendar(t *testing.T) *Calendar {
+	t.Helper()
+	calendar, err := NewCalendar(&Config{
+		PublicationDays:        []string{"monday", "tuesday", "wednesday", "thursday", "friday", "sat"},
+		PublicationHolidays:    []string{"2026-08-07"},
+		PublicationGracePeriod: 12 * time.Hour,
+	})
+	require.NoError(t, err)
+	return calendar
+}
+
+func TestNewCalendar(t *testing.T) {
+	_, err := NewCalendar(&Config{PublicationDays: []string{"funday"}})
+	require.ErrorIs(t, err, ErrInvalidCalendar)
+
+	_, err = NewCalendar(&Config{PublicationHolidays: []string{"2026-13-01"}})
+	require.ErrorIs(t, err, ErrInvalidCalendar)
+
+	calendar, err := NewCalendar(&Config{})
+	require.NoError(t, err)
+	for d := range 7 {
+		assert.True(t, calendar.Publishes(time.Date(2026, 8, 2+d, 0, 0, 0, 0, time.UTC)), "should publish every day by default")
+	}
+}
+
+func TestCalendar_Publishes(t *testing.T) {
+	calendar := newTestCalendar(t)
+
+	tests := []struct {
+		date string
+		want bool
+	}{
+		{"2026-08-03", true},  // Monday
+		{"2026-08-07", false}, // Holiday
+		{"2026-08-08", true},  // Saturday
+		{"2026-08-09", false}, // Sunday
+	}
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			date, err := time.Parse(time.DateOnly, tt.date)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, calendar.Publishes(date))
+		})
+	}
+
+	var got []string
+	for date := range calendar.Expected(time.Date(2026, 8, 6, 0, 0, 0, 0, time.UTC), time.Date(2026, 8, 10, 0, 0, 0, 0, time.UTC)) {
+		got = append(got, date.Format(time.DateOnly))
+	}
+	assert.Equal(t, []string{"2026-08-06", "2026-08-08", "2026-08-10"}, got)
+}
+
+func TestCalendar_Overdue(t *testing.T) {
+	calendar := newTestCalendar(t)
+	saturday := NewIssueFromDate(time.Date(2026, 8, 8, 0, 0, 0, 0, time.UTC), ".pdf")
+	thursday := NewIssueFromDate(time.Date(2026, 8, 6, 0, 0, 0, 0, time.UTC), ".pdf")
+
+	tests := []struct {
+		name   string
+		latest *Issue
+		now    time.Time
+		want   bool
+	}{
+		{"sunday", saturday, time.Date(2026, 8, 9, 20, 0, 0, 0, time.UTC), false},
+		{"monday within grace period", saturday, time.Date(2026, 8, 10, 11, 0, 0, 0, time.UTC), false},
+		{"monday after grace period", saturday, time.Date(2026, 8, 10, 13, 0, 0, 0, time.UTC), true},
+		{"holiday", thursday, time.Date(2026, 8, 7, 20, 0, 0, 0, time.UTC), false},
+		{"missing saturday", thursday, time.Date(2026, 8, 9, 20, 0, 0, 0, time.UTC), true},
+		{"no issue", nil, time.Date(2026, 8, 9, 20, 0, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, calendar.Overdue(tt.latest, tt.now))
+		})
+	}
+}
+
+func TestGapsHandler(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+	latest.Store(NewIssueFromDate(time.Date(2026, 8, 8, 0, 0, 0, 0, time.UTC), ".pdf"))
+
+	store := newMemStorage("2026/07/31.pdf", "2026/08/03.pdf", "2026/08/04.pdf", "2026/08/05.epub", "2026/08/06.pdf",
+		"2026/08/08.pdf")
+	handler := gapsHandler(store, newTestCalendar(t))
+
+	get := func(t *testing.T, target string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequestWithContext(t.Context(), http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	t.Run("range", func(t *testing.T) {
+		w := get(t, "/api/gaps?from=2026-07-30&to=2026-08-09")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var res struct {
+			Expected int      `json:"expected"`
+			Stored   int      `json:"stored"`
+			Missing  []string `json:"missing"`
+			Latest   string   `json:"latest"`
+			Overdue  bool     `json:"overdue"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+		assert.Equal(t, 8, res.Expected)
+		assert.Equal(t, 5, res.Stored)
+		assert.Equal(t, []string{"2026-07-30", "2026-08-01", "2026-08-05"}, res.Missing)
+		assert.Equal(t, "/2026-08-08.pdf", res.Latest)
+		assert.True(t, res.Overdue)
+	})
+
+	t.Run("ext", func(t *testing.T) {
+		w := get(t, "/api/gaps?from=2026-08-03&to=2026-08-06&ext=epub")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"missing":["2026-08-03","2026-08-04","2026-08-06"]`)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, target := range []string{
+			"/api/gaps?from=yesterday",
+			"/api/gaps?from=2026-08-09&to=2026-08-01",
+			"/api/gaps?from=2024-01-01&to=2026-08-01",
+		} {
+			assert.Equal(t, http.StatusBadRequest, get(t, target).Code, target)
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrForbiddenHost    = errors.New("host is not allowed")
+	ErrForbiddenAddress = errors.New("address is not allowed")
+	ErrTooManyRedirects = errors.New("too many redirects")
+	ErrForbiddenScheme  = errors.New("URL scheme must be http or https")
+)
+
+// NewUploadClient returns an HTTP client for fetching upstream issues.
+//
+// Every request, including each redirect hop, must target a host in conf.UploadAllowedHosts
+// (if set). Unless conf.UploadAllowPrivate is set, connections to loopback, private,
+// link-local and other non-public addresses are refused after DNS resolution, so
+// hostnames that resolve to internal addresses are caught too.
+func NewUploadClient(conf *Config) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if !conf.UploadAllowPrivate {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			return checkAddress(address)
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone() //nolint:errcheck,forcetypeassert // Always a *http.Transport
+	// A proxy would make the dialer check the proxy's address instead of the upstream's.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	// Fail upstreams that accept the connection but never respond. Slow bodies are still allowed.
+	transport.ResponseHeaderTimeout = 30 * time.Second
+
+	return &http.Client{
+		Transport: tracingTransport(transport),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > conf.UploadMaxRedirects {
+				return fmt.Errorf("%w: stopped after %d", ErrTooManyRedirects, conf.UploadMaxRedirects)
+			}
+			return checkUploadURL(conf, req.URL)
+		},
+	}
+}
+
+// checkUploadURL validates the scheme and host of u against conf.
+func checkUploadURL(conf *Config, u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrForbiddenScheme
+	}
+	if !hostAllowed(conf.UploadAllowedHosts, u.Hostname()) {
+		return fmt.Errorf("%w: %s", ErrForbiddenHost, u.Hostname())
+	}
+	return nil
+}
+
+// hostAllowed reports whether host matches one of allowed.
+// Entries prefixed with `*.` match any subdomain. An empty list allows every host.
+func hostAllowed(allowed []string, host string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, v := range allowed {
+		v = strings.ToLower(strings.TrimSuffix(v, "."))
+		if suffix, ok := strings.CutPrefix(v, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == v {
+			return true
+		}
+	}
+	return false
+}
+
+//nolint:gochecknoglobals
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "This" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),   // Benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),     // Reserved
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // Local-use NAT64
+	netip.MustParsePrefix("2001:db8::/32"),   // Documentation
+	netip.MustParsePrefix("fec0::/10"),       // Deprecated site-local
+	netip.MustParsePrefix("::ffff:0:0:0/96"), // SIIT
+}
+
+// embeddedIPv4Prefixes are IPv6 ranges that embed an IPv4 address at offset, which a gateway
+// may route to. Addresses in them are only as public as their embedded IPv4 address.
+//
+//nolint:gochecknoglobals
+var embeddedIPv4Prefixes = []struct {
+	prefix netip.Prefix
+	offset int
+}{
+	{netip.MustParsePrefix("64:ff9b::/96"), 12}, // Well-known NAT64
+	{netip.MustParsePrefix("::/96"), 12},        // IPv4-compatible
+	{netip.MustParsePrefix("2002::/16"), 2},     // 6to4
+}
+
+// checkAddress returns an error if the resolved host:port address is not publicly routable.
+func checkAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+
+	if !isPublic(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, ip)
+	}
+	return nil
+}
+
+func isPublic(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	for _, v := range embeddedIPv4Prefixes {
+		if v.prefix.Contains(ip) {
+			b := ip.As16()
+			return isPublic(netip.AddrFrom4([4]byte(b[v.offset : v.offset+4])))
+		}
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
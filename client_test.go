@@ -0,0 +1,139 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostAllowed(t *testing.T) {
+	allowed := []string{"example.com", "*.cdn.example.net", "*example.org"}
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"example.com", true},
+		{"EXAMPLE.com.", true},
+		{"www.example.com", false},
+		{"a.cdn.example.net", true},
+		{"a.b.cdn.example.net", true},
+		{"cdn.example.net", false},
+		{"evilcdn.example.net", false},
+		{"evilexample.org", false},
+		{"www.example.org", false},
+		{"169.254.169.254", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			assert.Equal(t, tt.want, hostAllowed(allowed, tt.host))
+		})
+	}
+
+	assert.True(t, hostAllowed(nil, "anything.example"), "empty list allows every host")
+}
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"64:ff9b::a9fe:a9fe", false},
+		{"64:ff9b::93.184.216.34", true},
+		{"::a9fe:a9fe", false},
+		{"::", false},
+		{"2002:a9fe:a9fe::1", false},
+		{"2002:5db8:d822::1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			assert.Equal(t, tt.want, isPublic(netip.MustParseAddr(tt.ip)))
+		})
+	}
+}
+
+func TestNewUploadClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/metadata":
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	get := func(t *testing.T, client *http.Client, path string) error {
+		t.Helper()
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		res, err := client.Do(req)
+		if err == nil {
+			_ = res.Body.Close()
+		}
+		return err
+	}
+
+	t.Run("rejects loopback", func(t *testing.T) {
+		client := NewUploadClient(&Config{UploadMaxRedirects: 5})
+		require.ErrorIs(t, get(t, client, "/"), ErrForbiddenAddress)
+	})
+
+	t.Run("allows loopback when configured", func(t *testing.T) {
+		client := NewUploadClient(&Config{UploadAllowPrivate: true, UploadMaxRedirects: 5})
+		require.NoError(t, get(t, client, "/"))
+	})
+
+	t.Run("caps redirects", func(t *testing.T) {
+		client := NewUploadClient(&Config{UploadAllowPrivate: true, UploadMaxRedirects: 3})
+		require.ErrorIs(t, get(t, client, "/loop"), ErrTooManyRedirects)
+	})
+
+	t.Run("checks redirect hosts", func(t *testing.T) {
+		u, err := url.Parse(server.URL)
+		require.NoError(t, err)
+		client := NewUploadClient(&Config{
+			UploadAllowPrivate: true,
+			UploadAllowedHosts: []string{u.Hostname()},
+			UploadMaxRedirects: 5,
+		})
+		require.ErrorIs(t, get(t, client, "/metadata"), ErrForbiddenHost)
+	})
+}
+
+func TestUploadHandler_ssrf(t *testing.T) {
+	upstream := newUpstream(t)
+	conf := newTestConfig()
+	conf.UploadAllowPrivate = false
+	fetcher := NewFetcher(conf, newMemStorage())
+	handler := uploadHandler(conf, fetcher, NewJobQueue(conf, fetcher))
+
+	q := url.Values{"url": {upstream + paperPath}}
+	r := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/api/upload?"+q.Encode(), nil)
+	r.Header.Set("Authorization", authKey)
+	w := httptest.NewRecorder()
+	handler(w, r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), ErrForbiddenAddress.Error())
+}
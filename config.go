@@ -11,20 +11,106 @@ import (
 type Config struct {
 	// The address to listen for HTTP requests on.
 	ListenAddress string `env:"LISTEN_ADDRESS,notEmpty" envDefault:":8080"`
-	// Redirect requests to `/` to the latest PDF.
+	// How long clients may take to send request headers. Request bodies, like file uploads, are not limited.
+	ReadHeaderTimeout time.Duration `env:"READ_HEADER_TIMEOUT,notEmpty" envDefault:"5s"`
+	// Redirect requests to `/` to the latest issue.
 	RedirectToLatest bool `env:"REDIRECT_TO_LATEST" envDefault:"true"`
+	// File extensions that `/` redirects to, in order of preference when the latest issue is stored in several formats. Any format is used if empty.
+	LatestFormats []string `env:"LATEST_FORMATS" envDefault:".pdf,.epub"`
+	// Issue to redirect to when a requested issue isn't stored, like on Sundays. One of `none`, `previous` or `next`. Can be overridden per request with the `fallback` param.
+	IssueFallback Fallback `env:"ISSUE_FALLBACK,notEmpty" envDefault:"none"`
 
-	// S3-compatible API endpoint.
-	S3Endpoint string `env:"S3_ENDPOINT,notEmpty"`
+	// Timezone of the paper's edition dates. Used to determine today's issue and to interpret dates in filenames and params.
+	PublicationTimezone *time.Location `env:"PUBLICATION_TIMEZONE" envDefault:"America/New_York"`
+	// Days of the week the paper is published, like `monday` or `mon`. Every day if empty.
+	PublicationDays []string `env:"PUBLICATION_DAYS" envDefault:"monday,tuesday,wednesday,thursday,friday,saturday"`
+	// Dates in YYYY-MM-DD format that the paper isn't published on, even if they fall on a publication day.
+	PublicationHolidays []string `env:"PUBLICATION_HOLIDAYS"`
+	// Time after midnight of a publication date by which its issue should be stored. Later issues are reported as missing by `/api/gaps` and as overdue.
+	PublicationGracePeriod time.Duration `env:"PUBLICATION_GRACE_PERIOD" envDefault:"12h"`
+
+	// Storage backend. One of `s3` or `fs`.
+	Storage string `env:"STORAGE,notEmpty" envDefault:"s3"`
+
+	// S3-compatible API endpoint. Required when `STORAGE` is `s3`.
+	S3Endpoint string `env:"S3_ENDPOINT"`
 	// S3 region.
 	S3Region string `env:"S3_REGION"`
-	// S3 bucket name.
-	S3Bucket string `env:"S3_BUCKET,notEmpty"`
+	// S3 bucket name. Required when `STORAGE` is `s3`.
+	S3Bucket string `env:"S3_BUCKET"`
+
+	// Directory to store issues in when `STORAGE` is `fs`.
+	FSPath string `env:"FS_PATH" envDefault:"data"`
 
 	// Authorization key for the `/api/upload` endpoint.
 	UploadAuthKey string `env:"UPLOAD_AUTH_KEY,notEmpty"`
 	// User agent to use when fetching a new PDF. Will be loaded from https://github.com/jnrbsn/user-agents if empty.
 	UploadUserAgent string `env:"UPLOAD_USER_AGENT"`
+	// Hosts that uploads may be fetched from, including redirects. Entries starting with `*.` match subdomains. Any host is allowed if empty.
+	UploadAllowedHosts []string `env:"UPLOAD_ALLOWED_HOSTS"`
+	// Allow uploads from loopback, private and link-local addresses.
+	UploadAllowPrivate bool `env:"UPLOAD_ALLOW_PRIVATE"`
+	// Maximum number of redirects to follow when fetching an upload.
+	UploadMaxRedirects int `env:"UPLOAD_MAX_REDIRECTS" envDefault:"5"`
+	// Content types that uploads may have. Any content type is allowed if empty.
+	UploadContentTypes []string `env:"UPLOAD_CONTENT_TYPES" envDefault:"application/pdf,application/epub+zip,application/octet-stream"`
+	// Minimum upload size in bytes.
+	UploadMinSize int64 `env:"UPLOAD_MIN_SIZE" envDefault:"1024"`
+	// Maximum upload size in bytes. Unlimited if 0.
+	UploadMaxSize int64 `env:"UPLOAD_MAX_SIZE" envDefault:"524288000"`
+	// Require PDF uploads to end with an `%%EOF` marker.
+	UploadCheckTrailer bool `env:"UPLOAD_CHECK_TRAILER"`
+	// Keep a copy of issues that are replaced by a forced upload under `backups/`.
+	UploadKeepBackups bool `env:"UPLOAD_KEEP_BACKUPS"`
+	// Retry policy for upstream downloads.
+	UpstreamRetry RetryPolicy `envPrefix:"UPSTREAM_RETRY_"`
+	// Upstream response status codes that are retried.
+	UpstreamRetryStatusCodes []int `env:"UPSTREAM_RETRY_STATUS_CODES" envDefault:"408,429,500,502,503,504"`
+	// Retry policy for storage writes.
+	StorageRetry RetryPolicy `envPrefix:"STORAGE_RETRY_"`
+	// Queue uploads as background jobs by default. Can be overridden per request with the `async` param.
+	UploadAsync bool `env:"UPLOAD_ASYNC"`
+	// Number of background upload workers.
+	UploadWorkers int `env:"UPLOAD_WORKERS,notEmpty" envDefault:"2"`
+	// Maximum number of queued background uploads.
+	UploadQueueSize int `env:"UPLOAD_QUEUE_SIZE,notEmpty" envDefault:"100"`
+	// How long finished upload jobs are kept for `/api/jobs/{id}`.
+	JobRetention time.Duration `env:"JOB_RETENTION,notEmpty" envDefault:"24h"`
+	// Maximum time a background upload job may take, including retries. Unlimited if 0.
+	JobTimeout time.Duration `env:"JOB_TIMEOUT" envDefault:"15m"`
+	// Number of issues that `/api/backfill` fetches concurrently.
+	BackfillConcurrency int `env:"BACKFILL_CONCURRENCY,notEmpty" envDefault:"2"`
+
+	// Cron expression for automatically fetching the daily issue. Disabled if empty.
+	FetchSchedule string `env:"FETCH_SCHEDULE"`
+	// Timezone used to evaluate `FETCH_SCHEDULE`.
+	FetchTimezone *time.Location `env:"FETCH_TIMEZONE" envDefault:"America/New_York"`
+	// URL to fetch the daily issue from. Required if `FETCH_SCHEDULE` is set.
+	FetchURL string `env:"FETCH_URL"`
+	// Time to wait between attempts when a scheduled fetch fails or the issue isn't published yet.
+	FetchRetryInterval time.Duration `env:"FETCH_RETRY_INTERVAL,notEmpty" envDefault:"15m"`
+	// Time after which a scheduled fetch stops retrying.
+	FetchRetryTimeout time.Duration `env:"FETCH_RETRY_TIMEOUT,notEmpty" envDefault:"6h"`
+
+	// How often to look for issues stored by other replicas, and how long the feeds are cached. Disabled if 0.
+	LatestRefreshInterval time.Duration `env:"LATEST_REFRESH_INTERVAL" envDefault:"5m"`
+	// How often to retry finding the latest issue while none is known, like when storage is empty or unreachable at startup.
+	LatestDiscoveryInterval time.Duration `env:"LATEST_DISCOVERY_INTERVAL,notEmpty" envDefault:"30s"`
+
+	// How long `/readyz` caches the result of the storage check.
+	ReadyCacheTTL time.Duration `env:"READY_CACHE_TTL,notEmpty" envDefault:"30s"`
+	// How long scheduled fetches may keep failing before `/readyz` reports the server as not ready.
+	ReadyMaxFetchFailure time.Duration `env:"READY_MAX_FETCH_FAILURE,notEmpty" envDefault:"24h"`
+
+	// Base URL for absolute links in feeds, like `https://example.com`. Derived from each request if empty.
+	PublicURL string `env:"PUBLIC_URL"`
+	// Number of recent issues listed in `/feed.atom` and `/feed.rss`.
+	FeedSize int `env:"FEED_SIZE,notEmpty" envDefault:"30"`
+
+	// Expose Prometheus metrics at `/metrics`.
+	MetricsEnabled bool `env:"METRICS_ENABLED" envDefault:"true"`
+	// Trace exporter. One of `none` or `otlp`. The OTLP exporter is configured with the standard `OTEL_EXPORTER_OTLP_*` variables.
+	TracesExporter string `env:"OTEL_TRACES_EXPORTER,notEmpty" envDefault:"none"`
 
 	// CIDR ranges of reverse proxies whose X-Forwarded-For headers are trusted
 	TrustedProxies []string `env:"TRUSTED_PROXIES"`
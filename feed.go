@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	feedTitle      = "The Wall Street Journal"
+	feedDateFormat = "Monday, January 2, 2006"
+)
+
+// Feed caches the most recent stored issues for the Atom and RSS endpoints.
+//
+// The cache is rebuilt when Invalidate is called for a saved issue or the latest PDF issue
+// changes. Issues saved by other replicas are picked up once the cache is older than
+// conf.LatestRefreshInterval.
+type Feed struct {
+	conf  *Config
+	store Storage
+
+	mu     sync.Mutex
+	latest *Issue
+	built  time.Time
+	items  []StoredIssue
+}
+
+// Invalidate drops the cached items if issue belongs in the feed. It is registered with
+// Fetcher.OnSave.
+func (f *Feed) Invalidate(issue *Issue) {
+	if issue.Ext != defaultExt {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.items = nil
+}
+
+func NewFeed(conf *Config, store Storage) *Feed {
+	return &Feed{conf: conf, store: store}
+}
+
+// Items returns up to conf.FeedSize PDF issues, newest first.
+func (f *Feed) Items(ctx context.Context) ([]StoredIssue, error) {
+	curr := latest.Get(defaultExt)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	expired := f.conf.LatestRefreshInterval > 0 && time.Since(f.built) >= f.conf.LatestRefreshInterval
+	if f.items != nil && f.latest == curr && !expired {
+		return f.items, nil
+	}
+
+	items := make([]StoredIssue, 0, f.conf.FeedSize)
+	for issue, err := range listIssues(ctx, f.store, "20") {
+		if err != nil {
+			return nil, err
+		}
+		if issue.Ext != defaultExt {
+			continue
+		}
+
+		if len(items) == f.conf.FeedSize {
+			items = append(items[:0], items[1:]...)
+		}
+		items = append(items, issue)
+	}
+	slices.Reverse(items)
+
+	f.latest, f.built, f.items = curr, time.Now(), items
+	return items, nil
+}
+
+// baseURL returns the absolute URL that feed links are relative to, without a trailing slash.
+func baseURL(conf *Config, r *http.Request) string {
+	if conf.PublicURL != "" {
+		return strings.TrimSuffix(conf.PublicURL, "/")
+	}
+
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// updated returns the time the newest item was stored.
+func updated(items []StoredIssue) time.Time {
+	var t time.Time
+	for _, item := range items {
+		if item.Info.LastModified.After(t) {
+			t = item.Info.LastModified
+		}
+	}
+	if t.IsZero() && len(items) != 0 {
+		t = items[0].Date
+	}
+	return t
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length int64  `xml:"length,attr,omitempty"`
+	Href   string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Published string     `xml:"published,omitempty"`
+	Updated   string     `xml:"updated"`
+	Content   string     `xml:"content,omitempty"`
+	Links     []atomLink `xml:"link"`
+}
+
+// atomHandler serves the most recent issues as an Atom feed.
+func (f *Feed) atomHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		items, err := f.Items(r.Context())
+		if err != nil {
+			handleStorageError(w, err)
+			return
+		}
+
+		base := baseURL(f.conf, r)
+		feed := atomFeed{
+			ID:    base + "/",
+			Title: feedTitle,
+			Links: []atomLink{
+				{Rel: "self", Type: "application/atom+xml", Href: base + r.URL.Path},
+				{Rel: "alternate", Type: "text/html", Href: base + "/archive"},
+			},
+			Entries: make([]atomEntry, 0, len(items)),
+		}
+		if t := updated(items); !t.IsZero() {
+			feed.Updated = t.UTC().Format(time.RFC3339)
+		} else {
+			feed.Updated = time.Now().UTC().Format(time.RFC3339)
+		}
+
+		for _, item := range items {
+			href := base + "/" + item.ShortPath()
+			entry := atomEntry{
+				ID:        href,
+				Title:     item.Date.Format(feedDateFormat),
+				Published: item.Date.Format(time.RFC3339),
+				Updated:   item.Date.Format(time.RFC3339),
+				Links: []atomLink{
+					{Rel: "enclosure", Type: contentType(item), Length: item.Info.Size, Href: href},
+				},
+			}
+			if !item.Info.LastModified.IsZero() {
+				entry.Updated = item.Info.LastModified.UTC().Format(time.RFC3339)
+			}
+			feed.Entries = append(feed.Entries, entry)
+		}
+
+		writeXML(w, "application/atom+xml; charset=utf-8", feed)
+	}
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title     string       `xml:"title"`
+	Link      string       `xml:"link"`
+	GUID      string       `xml:"guid"`
+	PubDate   string       `xml:"pubDate"`
+	Enclosure rssEnclosure `xml:"enclosure"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// rssHandler serves the most recent issues as an RSS 2.0 feed.
+func (f *Feed) rssHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		items, err := f.Items(r.Context())
+		if err != nil {
+			handleStorageError(w, err)
+			return
+		}
+
+		base := baseURL(f.conf, r)
+		feed := rssFeed{
+			Version: "2.0",
+			Channel: rssChannel{
+				Title:       feedTitle,
+				Link:        base + "/archive",
+				Description: "Recently stored issues of " + feedTitle,
+				Items:       make([]rssItem, 0, len(items)),
+			},
+		}
+		if t := updated(items); !t.IsZero() {
+			feed.Channel.LastBuildDate = t.UTC().Format(time.RFC1123Z)
+		}
+
+		for _, item := range items {
+			href := base + "/" + item.ShortPath()
+			feed.Channel.Items = append(feed.Channel.Items, rssItem{
+				Title:   item.Date.Format(feedDateFormat),
+				Link:    href,
+				GUID:    href,
+				PubDate: item.Date.Format(time.RFC1123Z),
+				Enclosure: rssEnclosure{
+					URL:    href,
+					Length: item.Info.Size,
+					Type:   contentType(item),
+				},
+			})
+		}
+
+		writeXML(w, "application/rss+xml; charset=utf-8", feed)
+	}
+}
+
+// contentType returns the stored content type of item, falling back to the type of its extension.
+func contentType(item StoredIssue) string {
+	if item.Info.ContentType != "" {
+		return item.Info.ContentType
+	}
+	if v := mime.TypeByExtension(item.Ext); v != "" {
+		return v
+	}
+	return "application/octet-stream"
+}
+
+func writeXML(w http.ResponseWriter, contentType string, v any) {
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		slog.Error("Failed to encode feed", "error", err)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveFeed(t *testing.T, handler http.HandlerFunc, target string, v any) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequestWithContext(t.Context(), http.MethodGet, target, nil)
+	w := httptest.NewRecorder()
+	handler(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, xml.Unmarshal(w.Body.Bytes(), v))
+	return w
+}
+
+func TestFeed_Items(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+	latest.Store(NewIssueFromDate(time.Date(2026, 8, 4, 0, 0, 0, 0, time.UTC), ".pdf"))
+
+	store := newMemStorage("2026/08/03.pdf", "2026/08/04.epub", "2026/08/04.pdf", "backups/2026/08/01.pdf")
+	feed := NewFeed(&Config{FeedSize: 3}, store)
+
+	items, err := feed.Items(t.Context())
+	require.NoError(t, err)
+	keys := func() []string {
+		keys := make([]string, 0, len(items))
+		for _, item := range items {
+			keys = append(keys, item.Info.Key)
+		}
+		return keys
+	}
+	assert.Equal(t, []string{"2026/08/04.pdf", "2026/08/03.pdf"}, keys())
+
+	// Saving an older issue invalidates the cache without changing the latest issue
+	issue := NewIssueFromDate(time.Date(2026, 8, 2, 0, 0, 0, 0, time.UTC), ".pdf")
+	fetcher := NewFetcher(&Config{}, store)
+	fetcher.OnSave(feed.Invalidate)
+	body := strings.NewReader("%PDF-1.4 fake")
+	require.NoError(t, fetcher.Save(t.Context(), issue, body, -1, "application/pdf", FetchOptions{}))
+	items, err = feed.Items(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2026/08/04.pdf", "2026/08/03.pdf", "2026/08/02.pdf"}, keys())
+
+	// Cached until another issue is saved or the latest issue advances
+	store.add("2026/08/05.pdf", "%PDF-1.4")
+	items, err = feed.Items(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2026/08/04.pdf", "2026/08/03.pdf", "2026/08/02.pdf"}, keys())
+
+	storeLatest(NewIssueFromDate(time.Date(2026, 8, 5, 0, 0, 0, 0, time.UTC), ".pdf"))
+	items, err = feed.Items(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2026/08/05.pdf", "2026/08/04.pdf", "2026/08/03.pdf"}, keys())
+}
+
+func TestFeed_Items_refreshInterval(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+
+	store := newMemStorage("2026/08/04.pdf")
+	conf := &Config{FeedSize: 10, LatestRefreshInterval: time.Hour}
+	feed := NewFeed(conf, store)
+
+	items, err := feed.Items(t.Context())
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+
+	// Stored by another replica, so the latest issue doesn't change
+	store.add("2026/08/03.pdf", "%PDF-1.4")
+	items, err = feed.Items(t.Context())
+	require.NoError(t, err)
+	assert.Len(t, items, 1, "cached until the refresh interval")
+
+	conf.LatestRefreshInterval = time.Nanosecond
+	items, err = feed.Items(t.Context())
+	require.NoError(t, err)
+	assert.Len(t, items, 2)
+}
+
+func TestFeed_atomHandler(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+	store := newMemStorage("2026/08/04.pdf", "2026/08/05.pdf")
+	feed := NewFeed(&Config{FeedSize: 10, PublicURL: "https://example.com/"}, store)
+
+	var got atomFeed
+	w := serveFeed(t, feed.atomHandler(), "/feed.atom", &got)
+	assert.Equal(t, "application/atom+xml; charset=utf-8", w.Header().Get("Content-Type"))
+
+	assert.Equal(t, "https://example.com/", got.ID)
+	assert.Equal(t, "https://example.com/feed.atom", got.Links[0].Href)
+	require.Len(t, got.Entries, 2)
+	entry := got.Entries[0]
+	assert.Equal(t, "https://example.com/2026-08-05.pdf", entry.ID)
+	assert.Equal(t, "Wednesday, August 5, 2026", entry.Title)
+	assert.Equal(t, "2026-08-05T00:00:00Z", entry.Published)
+	require.Len(t, entry.Links, 1)
+	assert.Equal(t, atomLink{
+		Rel:    "enclosure",
+		Type:   "application/pdf",
+		Length: int64(len("%PDF-1.4 2026/08/05.pdf")),
+		Href:   "https://example.com/2026-08-05.pdf",
+	}, entry.Links[0])
+}
+
+func TestFeed_rssHandler(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+	store := newMemStorage("2026/08/04.pdf", "2026/08/05.pdf")
+	feed := NewFeed(&Config{FeedSize: 1}, store)
+
+	var got rssFeed
+	w := serveFeed(t, feed.rssHandler(), "http://wsj.example.com/feed.rss", &got)
+	assert.Equal(t, "application/rss+xml; charset=utf-8", w.Header().Get("Content-Type"))
+
+	assert.Equal(t, "2.0", got.Version)
+	assert.Equal(t, "http://wsj.example.com/archive", got.Channel.Link)
+	require.Len(t, got.Channel.Items, 1)
+	item := got.Channel.Items[0]
+	assert.Equal(t, "http://wsj.example.com/2026-08-05.pdf", item.Link)
+	assert.Equal(t, "Wed, 05 Aug 2026 00:00:00 +0000", item.PubDate)
+	assert.Equal(t, rssEnclosure{
+		URL:    "http://wsj.example.com/2026-08-05.pdf",
+		Length: int64(len("%PDF-1.4 2026/08/05.pdf")),
+		Type:   "application/pdf",
+	}, item.Enclosure)
+}
@@ -1,14 +1,26 @@
 package main
 
 import (
+	"bytes"
+	"errors"
+	"html/template"
+	"io/fs"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/minio/minio-go/v7"
+	"github.com/go-chi/chi/v5/middleware"
 )
 
-func get(conf *Config, s3 *minio.Client) http.HandlerFunc {
+// get serves stored files. Issues can be requested by short path, or by an alias
+// like `/latest.pdf`, `/today` or `/monday` that is resolved by resolveIssue.
+//
+// Aliases redirect to the issue's short path, unless the `inline` param is true.
+// If the requested issue isn't stored, the `fallback` param (defaulting to
+// conf.IssueFallback) can redirect to the previous or next stored issue instead.
+func get(conf *Config, store Storage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		filename := chi.URLParam(r, "*")
 		if filename == "" {
@@ -16,50 +28,124 @@ func get(conf *Config, s3 *minio.Client) http.HandlerFunc {
 			return
 		}
 
-		if issue, err := NewIssueFromPath(filename); err == nil {
-			filename = issue.FullPath()
+		fallback := conf.IssueFallback
+		if v := r.URL.Query().Get("fallback"); v != "" {
+			if err := fallback.UnmarshalText([]byte(v)); err != nil {
+				handleHTTPError(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		// notFound redirects to the nearest issue if a fallback is selected.
+		notFound := func(requested *Issue, err error) {
+			if requested == nil || fallback == FallbackNone || fallback == "" || !errors.Is(err, fs.ErrNotExist) {
+				handleStorageError(w, err)
+				return
+			}
+
+			issue, err := nearestIssue(r.Context(), store, requested.Date, requested.Ext, fallback, conf.LatestFormats)
+			if err != nil {
+				handleStorageError(w, err)
+				return
+			}
+			w.Header().Set("Cache-Control", "no-cache")
+			http.Redirect(w, r, "/"+issue.ShortPath(), http.StatusTemporaryRedirect)
 		}
 
-		obj, err := s3.GetObject(r.Context(), conf.S3Bucket, filename, minio.GetObjectOptions{})
+		issue, alias, err := resolveIssue(r.Context(), conf, store, filename)
 		if err != nil {
-			handleMinioError(w, err)
+			notFound(issue, err)
 			return
 		}
-		defer func() {
-			_ = obj.Close()
-		}()
 
-		stat, err := obj.Stat()
+		cacheControl := "public, max-age=86400"
+		if alias {
+			var inline bool
+			if v := r.URL.Query().Get("inline"); v != "" {
+				if inline, err = strconv.ParseBool(v); err != nil {
+					handleHTTPError(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			if !inline {
+				w.Header().Set("Cache-Control", "no-cache")
+				http.Redirect(w, r, "/"+issue.ShortPath(), http.StatusTemporaryRedirect)
+				return
+			}
+			// The alias will point to another issue later.
+			cacheControl = "no-cache"
+		}
+		if issue != nil {
+			filename = issue.FullPath()
+		}
+
+		obj, err := store.Get(r.Context(), filename)
 		if err != nil {
-			handleMinioError(w, err)
+			notFound(issue, err)
 			return
 		}
+		defer func() {
+			_ = obj.Close()
+		}()
 
+		stat := obj.Info()
 		if v := stat.ETag; v != "" {
 			w.Header().Set("ETag", strconv.Quote(v))
 		}
 
-		w.Header().Set("Cache-Control", "public, max-age=86400")
-		http.ServeContent(w, r, filename, stat.LastModified, obj)
+		w.Header().Set("Cache-Control", cacheControl)
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		http.ServeContent(ww, r, filename, stat.LastModified, obj)
+		servedBytes.Add(float64(ww.BytesWritten()))
 	}
 }
 
-func redirectLatest() http.HandlerFunc {
+type unavailablePage struct {
+	Title, Message string
+	RetryAfter     time.Duration
+}
+
+// redirectLatest redirects to the latest issue in one of conf.LatestFormats.
+//
+// Until an issue is known, it renders a page explaining why instead. The status is
+// 503 if the last attempt to find the latest issue failed, or 404 if no issue is stored.
+func redirectLatest(conf *Config) (http.HandlerFunc, error) {
+	tmpl, err := template.ParseFS(templates, "templates/unavailable.html")
+	if err != nil {
+		return nil, err
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
-		issue := latest.Load()
-		if issue == nil {
-			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		if issue := latest.Preferred(conf.LatestFormats); issue != nil {
+			http.Redirect(w, r, "/"+issue.ShortPath(), http.StatusTemporaryRedirect)
 			return
 		}
 
-		http.Redirect(w, r, "/"+issue.ShortPath(), http.StatusTemporaryRedirect)
-	}
-}
+		code := http.StatusNotFound
+		page := unavailablePage{
+			Title:   "No issues yet",
+			Message: "No issues have been stored yet. Check back after the next issue is uploaded.",
+		}
+		if err := latest.Err(); err != nil {
+			code = http.StatusServiceUnavailable
+			page = unavailablePage{
+				Title:      "Temporarily unavailable",
+				Message:    "The latest issue couldn't be found because storage is unavailable.",
+				RetryAfter: conf.LatestDiscoveryInterval,
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(int(page.RetryAfter.Seconds())))
+		}
 
-func handleMinioError(w http.ResponseWriter, err error) {
-	if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
-	} else {
-		handleHTTPError(w, err.Error(), http.StatusInternalServerError)
-	}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, page); err != nil {
+			slog.Error("Failed to render page", "error", err)
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(code)
+		_, _ = buf.WriteTo(w)
+	}, nil
 }
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withURLParam adds a chi URL param to r, as if it had been routed by chi.
+func withURLParam(r *http.Request, key, value string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add(key, value)
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestGet(t *testing.T) {
+	store := newMemStorage("2026/08/05.pdf")
+	handler := get(newTestConfig(), store)
+
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+	}{
+		{"short path", "2026-08-05.pdf", http.StatusOK},
+		{"full path", "2026/08/05.pdf", http.StatusOK},
+		{"missing", "2026-08-04.pdf", http.StatusNotFound},
+		{"empty", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/"+tt.path, nil)
+			r = withURLParam(r, "*", tt.path)
+			w := httptest.NewRecorder()
+
+			handler(w, r)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			if tt.wantCode == http.StatusOK {
+				assert.NotEmpty(t, w.Header().Get("ETag"))
+				assert.Equal(t, "%PDF-1.4 2026/08/05.pdf", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGet_alias(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+	latest.Store(nil)
+
+	today := issueToday()
+	yesterday := today.AddDate(0, 0, -1)
+	store := newMemStorage(
+		"2026/08/05.pdf",
+		yesterday.Format("2006/01/02.pdf"),
+		today.Format("2006/01/02.pdf"),
+		today.Format("2006/01/02.epub"),
+	)
+	storeLatest(NewIssueFromDate(today, ".pdf"))
+	storeLatest(NewIssueFromDate(today, ".epub"))
+
+	conf := newTestConfig()
+	conf.LatestFormats = []string{".pdf", ".epub"}
+	handler := get(conf, store)
+
+	todayPDF := "/" + today.Format("2006-01-02.pdf")
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+		want     string
+	}{
+		{"latest", "latest", http.StatusTemporaryRedirect, todayPDF},
+		{"latest with ext", "latest.epub", http.StatusTemporaryRedirect, "/" + today.Format("2006-01-02.epub")},
+		{"latest missing ext", "latest.mobi", http.StatusNotFound, ""},
+		{"today", "today", http.StatusTemporaryRedirect, todayPDF},
+		{"today with ext", "today.epub", http.StatusTemporaryRedirect, "/" + today.Format("2006-01-02.epub")},
+		{"yesterday", "yesterday", http.StatusTemporaryRedirect, "/" + yesterday.Format("2006-01-02.pdf")},
+		{"yesterday missing ext", "yesterday.epub", http.StatusNotFound, ""},
+		{"weekday", strings.ToLower(today.Weekday().String()), http.StatusTemporaryRedirect, todayPDF},
+		{"weekday without issue", strings.ToLower(today.AddDate(0, 0, -2).Weekday().String()), http.StatusNotFound, ""},
+		{"date", "2026-08-05", http.StatusTemporaryRedirect, "/2026-08-05.pdf"},
+		{"date without issue", "2026-08-04", http.StatusNotFound, ""},
+		{"inline", "today?inline=true", http.StatusOK, "%PDF-1.4 " + today.Format("2006/01/02.pdf")},
+		{"invalid inline", "today?inline=nope", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/"+tt.path, nil)
+			r = withURLParam(r, "*", r.URL.Path[1:])
+			w := httptest.NewRecorder()
+
+			handler(w, r)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			switch tt.wantCode {
+			case http.StatusTemporaryRedirect:
+				assert.Equal(t, tt.want, w.Header().Get("Location"))
+				assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+			case http.StatusOK:
+				assert.Equal(t, tt.want, w.Body.String())
+				assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"), "aliases should not be cached")
+			}
+		})
+	}
+}
+
+func TestGet_fallback(t *testing.T) {
+	store := newMemStorage("2026/08/08.pdf", "2026/08/10.pdf", "2026/08/10.epub")
+	conf := newTestConfig()
+	conf.IssueFallback = FallbackPrevious
+	handler := get(conf, store)
+
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+		want     string
+	}{
+		{"stored", "2026-08-10.pdf", http.StatusOK, ""},
+		{"config default", "2026-08-09.pdf", http.StatusTemporaryRedirect, "/2026-08-08.pdf"},
+		{"next", "2026-08-09.pdf?fallback=next", http.StatusTemporaryRedirect, "/2026-08-10.pdf"},
+		{"next matches ext", "2026-08-09.epub?fallback=next", http.StatusTemporaryRedirect, "/2026-08-10.epub"},
+		{"alias", "2026-08-09?fallback=next", http.StatusTemporaryRedirect, "/2026-08-10.pdf"},
+		{"none", "2026-08-09.pdf?fallback=none", http.StatusNotFound, ""},
+		{"no nearest", "2026-08-11.pdf?fallback=next", http.StatusNotFound, ""},
+		{"not an issue", "robots.txt", http.StatusNotFound, ""},
+		{"invalid", "2026-08-09.pdf?fallback=closest", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/"+tt.path, nil)
+			r = withURLParam(r, "*", r.URL.Path[1:])
+			w := httptest.NewRecorder()
+
+			handler(w, r)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			if tt.want != "" {
+				assert.Equal(t, tt.want, w.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestRedirectLatest(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+	latest.Store(nil)
+	storeLatest(NewIssueFromDate(time.Date(2026, 8, 5, 0, 0, 0, 0, time.UTC), ".pdf"))
+	storeLatest(NewIssueFromDate(time.Date(2026, 8, 5, 0, 0, 0, 0, time.UTC), ".epub"))
+	storeLatest(NewIssueFromDate(time.Date(2026, 8, 6, 0, 0, 0, 0, time.UTC), ".mobi"))
+
+	tests := []struct {
+		name    string
+		formats []string
+		want    string
+	}{
+		{"preferred", []string{".pdf", ".epub"}, "/2026-08-05.pdf"},
+		{"order", []string{".epub", ".pdf"}, "/2026-08-05.epub"},
+		{"any", nil, "/2026-08-06.mobi"},
+		{"no matching format", []string{".txt"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := newTestConfig()
+			conf.LatestFormats = tt.formats
+			r := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+			w := httptest.NewRecorder()
+
+			handler, err := redirectLatest(conf)
+			require.NoError(t, err)
+			handler(w, r)
+
+			if tt.want == "" {
+				assert.Equal(t, http.StatusNotFound, w.Code)
+				assert.Contains(t, w.Body.String(), "No issues yet")
+				return
+			}
+			assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+			assert.Equal(t, tt.want, w.Header().Get("Location"))
+		})
+	}
+}
+
+func TestRedirectLatest_unavailable(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+	latest.Store(nil)
+
+	store := &brokenStorage{memStorage: newMemStorage("2026/08/05.pdf")}
+	storeErr := errors.New("connection refused")
+	store.err.Store(&storeErr)
+
+	conf := newTestConfig()
+	conf.LatestDiscoveryInterval = 30 * time.Second
+	handler, err := redirectLatest(conf)
+	require.NoError(t, err)
+
+	get := func(t *testing.T) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	_, err = refreshLatest(t.Context(), store)
+	require.Error(t, err)
+	w := get(t)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "Temporarily unavailable")
+	assert.NotContains(t, w.Body.String(), "connection refused", "errors should not be exposed")
+
+	store.err.Store(nil)
+	_, err = refreshLatest(t.Context(), store)
+	require.NoError(t, err)
+	w = get(t)
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	assert.Equal(t, "/2026-08-05.pdf", w.Header().Get("Location"))
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// indexKey is where the issue index is stored. It must not start with the
+// year prefix that listIssues reads.
+const (
+	indexKey     = "index.json"
+	indexVersion = 1
+)
+
+var ErrIndexVersion = errors.New("unsupported index version")
+
+// Index is a manifest of every stored issue, so that the archive doesn't need to be
+// listed to find the latest issue.
+type Index struct {
+	Version int          `json:"version"`
+	Updated time.Time    `json:"updated"`
+	Issues  []IndexEntry `json:"issues"`
+}
+
+// IndexEntry describes a stored issue. Entries are sorted by key.
+type IndexEntry struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified time.Time `json:"last_modified,omitzero"`
+}
+
+func NewIndexEntry(info ObjectInfo) IndexEntry {
+	return IndexEntry{
+		Key:          info.Key,
+		Size:         info.Size,
+		ETag:         info.ETag,
+		LastModified: info.LastModified,
+	}
+}
+
+// loadIndex reads the index from store.
+// If it has not been built yet, the error wraps fs.ErrNotExist.
+func loadIndex(ctx context.Context, store Storage) (*Index, error) {
+	obj, err := store.Get(ctx, indexKey)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = obj.Close()
+	}()
+
+	var idx Index
+	if err := json.NewDecoder(obj).Decode(&idx); err != nil {
+		return nil, fmt.Errorf("failed to decode index: %w", err)
+	}
+	if idx.Version != indexVersion {
+		return nil, fmt.Errorf("%w: %d", ErrIndexVersion, idx.Version)
+	}
+	return &idx, nil
+}
+
+// save replaces the index in store. The object is written with a single Put,
+// so readers never see a partial index.
+func (idx *Index) save(ctx context.Context, store Storage) error {
+	idx.Version = indexVersion
+	idx.Updated = time.Now().UTC()
+
+	b, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	return store.Put(ctx, indexKey, bytes.NewReader(b), int64(len(b)), PutOptions{ContentType: "application/json"})
+}
+
+// set adds or replaces the entry for info.Key.
+func (idx *Index) set(info ObjectInfo) {
+	i, found := slices.BinarySearchFunc(idx.Issues, info.Key, func(e IndexEntry, key string) int {
+		return strings.Compare(e.Key, key)
+	})
+	if found {
+		idx.Issues[i] = NewIndexEntry(info)
+	} else {
+		idx.Issues = slices.Insert(idx.Issues, i, NewIndexEntry(info))
+	}
+}
+
+// remove deletes the entry for key, if any.
+func (idx *Index) remove(key string) {
+	idx.Issues = slices.DeleteFunc(idx.Issues, func(e IndexEntry) bool {
+		return e.Key == key
+	})
+}
+
+// Latest returns the newest issue of each format, keyed by extension.
+func (idx *Index) Latest() map[string]*Issue {
+	found := make(map[string]*Issue)
+	for _, entry := range idx.Issues {
+		if issue, err := NewIssueFromKey(entry.Key); err == nil {
+			trackLatest(found, issue)
+		}
+	}
+	return found
+}
+
+// buildIndex lists every stored issue.
+func buildIndex(ctx context.Context, store Storage) (*Index, error) {
+	idx := &Index{Issues: make([]IndexEntry, 0)}
+	for issue, err := range listIssues(ctx, store, "20") {
+		if err != nil {
+			return nil, err
+		}
+		idx.Issues = append(idx.Issues, NewIndexEntry(issue.Info))
+	}
+	return idx, nil
+}
+
+// rebuildIndex regenerates the index from a full listing and saves it.
+func rebuildIndex(ctx context.Context, store Storage) (*Index, error) {
+	idx, err := buildIndex(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	if err := idx.save(ctx, store); err != nil {
+		return nil, err
+	}
+	slog.Info("Rebuilt index", "issues", len(idx.Issues))
+	return idx, nil
+}
+
+// ensureIndex rebuilds the index if it is missing or can't be read.
+func ensureIndex(ctx context.Context, store Storage) error {
+	_, err := loadIndex(ctx, store)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, fs.ErrNotExist):
+		slog.Info("Index not found, rebuilding")
+	default:
+		slog.Warn("Failed to load index, rebuilding", "error", err)
+	}
+	_, err = rebuildIndex(ctx, store)
+	return err
+}
+
+// indexedStorage keeps the index up to date as issues are stored and deleted.
+//
+// Updates are serialized within the process. Replicas that write concurrently may
+// drop each other's entries, which the `rebuild-index` command repairs.
+type indexedStorage struct {
+	Storage
+	mu sync.Mutex
+}
+
+func (s *indexedStorage) Put(ctx context.Context, key string, r io.Reader, size int64, opts PutOptions) error {
+	if err := s.Storage.Put(ctx, key, r, size, opts); err != nil {
+		return err
+	}
+	if _, err := NewIssueFromKey(key); err != nil {
+		return nil
+	}
+
+	info, err := s.Storage.Stat(ctx, key)
+	if err != nil {
+		info = ObjectInfo{Key: key, Size: size, ContentType: opts.ContentType, LastModified: time.Now()}
+	}
+	s.update(ctx, key, func(idx *Index) { idx.set(info) })
+	return nil
+}
+
+func (s *indexedStorage) Delete(ctx context.Context, key string) error {
+	if err := s.Storage.Delete(ctx, key); err != nil {
+		return err
+	}
+	if _, err := NewIssueFromKey(key); err != nil {
+		return nil
+	}
+
+	s.update(ctx, key, func(idx *Index) { idx.remove(key) })
+	return nil
+}
+
+// update applies fn to the stored index. The object has already been written,
+// so failures are logged rather than failing the operation.
+func (s *indexedStorage) update(ctx context.Context, key string, fn func(idx *Index)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx, err := loadIndex(ctx, s.Storage)
+	if err != nil {
+		// A full listing already includes the change.
+		if _, err := rebuildIndex(ctx, s.Storage); err != nil {
+			slog.Error("Failed to rebuild index", "key", key, "error", err)
+		}
+		return
+	}
+
+	fn(idx)
+	if err := idx.save(ctx, s.Storage); err != nil {
+		slog.Error("Failed to update index", "key", key, "error", err)
+	}
+}
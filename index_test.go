@@ -0,0 +1,99 @@
+package main
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func indexKeys(t *testing.T, store Storage) []string {
+	t.Helper()
+	idx, err := loadIndex(t.Context(), store)
+	require.NoError(t, err)
+	keys := make([]string, 0, len(idx.Issues))
+	for _, entry := range idx.Issues {
+		keys = append(keys, entry.Key)
+	}
+	return keys
+}
+
+func TestIndexedStorage(t *testing.T) {
+	mem := newMemStorage("2026/08/04.pdf")
+	store := &indexedStorage{Storage: mem}
+	put := func(key string) {
+		t.Helper()
+		require.NoError(t, store.Put(t.Context(), key, strings.NewReader("%PDF-"), 5, PutOptions{}))
+	}
+
+	_, err := loadIndex(t.Context(), store)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	// A missing index is built from a full listing
+	put("2026/08/06.pdf")
+	assert.Equal(t, []string{"2026/08/04.pdf", "2026/08/06.pdf"}, indexKeys(t, store))
+
+	put("2026/08/05.pdf")
+	put("2026/08/05.pdf")
+	put("backups/2026/08/05.20260805T120000Z.pdf")
+	assert.Equal(t, []string{"2026/08/04.pdf", "2026/08/05.pdf", "2026/08/06.pdf"}, indexKeys(t, store))
+
+	require.NoError(t, store.Delete(t.Context(), "2026/08/06.pdf"))
+	assert.Equal(t, []string{"2026/08/04.pdf", "2026/08/05.pdf"}, indexKeys(t, store))
+
+	idx, err := loadIndex(t.Context(), store)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), idx.Issues[1].Size)
+	assert.NotEmpty(t, idx.Issues[1].ETag)
+	assert.False(t, idx.Updated.IsZero())
+}
+
+func TestIndex_Latest(t *testing.T) {
+	idx := &Index{Issues: []IndexEntry{
+		{Key: "2026/08/04.pdf"},
+		{Key: "2026/08/05.epub"},
+		{Key: "2026/08/05.pdf"},
+		{Key: "2026/08/06.epub"},
+	}}
+	found := idx.Latest()
+	assert.Len(t, found, 2)
+	assert.Equal(t, "2026-08-05.pdf", found[".pdf"].ShortPath())
+	assert.Equal(t, "2026-08-06.epub", found[".epub"].ShortPath())
+	assert.Nil(t, found[".mobi"])
+}
+
+func TestFindLatest_index(t *testing.T) {
+	store := newMemStorage("2025/01/02.pdf")
+	idx := &Index{Issues: []IndexEntry{{Key: "2025/01/01.pdf"}}}
+	require.NoError(t, idx.save(t.Context(), store))
+
+	got, err := findLatest(t.Context(), store)
+	require.NoError(t, err)
+	assert.Equal(t, "2025-01-01.pdf", got[".pdf"].ShortPath(), "should trust the index over a listing")
+
+	_, err = rebuildIndex(t.Context(), store)
+	require.NoError(t, err)
+	got, err = findLatest(t.Context(), store)
+	require.NoError(t, err)
+	assert.Equal(t, "2025-01-02.pdf", got[".pdf"].ShortPath())
+
+	// Today's issue is found even if it is missing from the index
+	store.add(issueToday().Format("2006/01/02.pdf"), "%PDF-1.4")
+	got, err = findLatest(t.Context(), store)
+	require.NoError(t, err)
+	assert.Equal(t, issueToday().Format("2006-01-02.pdf"), got[".pdf"].ShortPath())
+}
+
+func TestLoadIndex_version(t *testing.T) {
+	store := newMemStorage()
+	store.add(indexKey, `{"version":99,"issues":[]}`)
+	_, err := loadIndex(t.Context(), store)
+	require.ErrorIs(t, err, ErrIndexVersion)
+
+	require.NoError(t, ensureIndex(t.Context(), store))
+	idx, err := loadIndex(t.Context(), store)
+	require.NoError(t, err)
+	assert.Empty(t, idx.Issues)
+}
@@ -11,6 +11,26 @@ import (
 
 var ErrInvalidFilename = errors.New("invalid filename")
 
+// issueKeyDateFormat is the date layout of storage keys, which sort in date order.
+const issueKeyDateFormat = "2006/01/02"
+
+// issueLocation is the timezone of issue dates. It is set from conf.PublicationTimezone
+// at startup so that an issue's date is midnight of its edition date in that zone.
+//
+//nolint:gochecknoglobals
+var issueLocation = time.UTC
+
+// parseIssueDate parses a date without a zone as an issue date.
+func parseIssueDate(layout, value string) (time.Time, error) {
+	return time.ParseInLocation(layout, value, issueLocation)
+}
+
+// issueToday returns the date of today's issue.
+func issueToday() time.Time {
+	now := time.Now().In(issueLocation)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, issueLocation)
+}
+
 func NewIssueFromUpstream(p string) (*Issue, error) {
 	ext := path.Ext(p)
 	p = path.Base(strings.TrimSuffix(p, ext))
@@ -28,7 +48,7 @@ func NewIssueFromUpstream(p string) (*Issue, error) {
 		return nil, fmt.Errorf("%w: %s", ErrInvalidFilename, "missing non-random prefix")
 	}
 
-	d, err := time.Parse("1-2-2006", p)
+	d, err := parseIssueDate("1-2-2006", p)
 	if err != nil {
 		log.Warn("Failed to parse filename date", "error", err)
 		return nil, fmt.Errorf("%w: %w", ErrInvalidFilename, err)
@@ -41,7 +61,19 @@ func NewIssueFromPath(p string) (*Issue, error) {
 	ext := path.Ext(p)
 	p = path.Base(strings.TrimSuffix(p, ext))
 
-	d, err := time.Parse("2006-01-02", p)
+	d, err := parseIssueDate("2006-01-02", p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Issue{Date: d, Ext: ext}, nil
+}
+
+// NewIssueFromKey parses a storage key in the format returned by Issue.FullPath.
+func NewIssueFromKey(key string) (*Issue, error) {
+	ext := path.Ext(key)
+
+	d, err := parseIssueDate("2006/01/02", strings.TrimSuffix(key, ext))
 	if err != nil {
 		return nil, err
 	}
@@ -59,7 +91,7 @@ type Issue struct {
 }
 
 func (i Issue) FullPath() string {
-	return i.Date.Format("2006/01/02") + i.Ext
+	return i.Date.Format(issueKeyDateFormat) + i.Ext
 }
 
 func (i Issue) ShortPath() string {
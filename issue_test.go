@@ -131,3 +131,64 @@ func TestNewIssueFromPath(t *testing.T) {
 		})
 	}
 }
+
+func TestNewIssueFromKey(t *testing.T) {
+	type args struct {
+		key string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    *Issue
+		wantErr require.ErrorAssertionFunc
+	}{
+		{"valid", args{"2025/01/02.pdf"}, &Issue{Date: date, Ext: ".pdf"}, require.NoError},
+		{"epub", args{"2025/01/02.epub"}, &Issue{Date: date, Ext: ".epub"}, require.NoError},
+		{"no ext", args{"2025/01/02"}, &Issue{Date: date}, require.NoError},
+		{"short path", args{"2025-01-02.pdf"}, nil, require.Error},
+		{"backup", args{"backups/2025/01/02.20250102T000000Z.pdf"}, nil, require.Error},
+		{"empty", args{""}, nil, require.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewIssueFromKey(tt.args.key)
+			tt.wantErr(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// setIssueLocation sets the publication timezone for the duration of the test.
+func setIssueLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	require.NoError(t, err)
+	prev := issueLocation
+	issueLocation = loc
+	t.Cleanup(func() { issueLocation = prev })
+	return loc
+}
+
+func TestIssueLocation(t *testing.T) {
+	loc := setIssueLocation(t, "America/New_York")
+	want := time.Date(2025, 1, 2, 0, 0, 0, 0, loc)
+
+	issue, err := NewIssueFromUpstream("a1b2-issue-1-2-2025.pdf")
+	require.NoError(t, err)
+	assert.True(t, want.Equal(issue.Date), "upstream date should be midnight in the publication timezone")
+
+	issue, err = NewIssueFromPath("2025-01-02.pdf")
+	require.NoError(t, err)
+	assert.True(t, want.Equal(issue.Date))
+
+	issue, err = NewIssueFromKey("2025/01/02.pdf")
+	require.NoError(t, err)
+	assert.True(t, want.Equal(issue.Date))
+	assert.Equal(t, "2025/01/02.pdf", issue.FullPath())
+
+	now := time.Now().In(loc)
+	today := issueToday()
+	assert.Equal(t, now.Format(time.DateOnly), today.Format(time.DateOnly))
+	assert.Equal(t, loc, today.Location())
+	assert.Zero(t, today.Hour())
+}
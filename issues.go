@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StoredIssue is an issue along with the metadata of its stored object.
+type StoredIssue struct {
+	*Issue
+	Info ObjectInfo
+}
+
+// listIssues iterates over the issues stored under prefix in key order.
+// Objects whose keys aren't in the Issue.FullPath format are skipped.
+func listIssues(ctx context.Context, store Storage, prefix string) iter.Seq2[StoredIssue, error] {
+	return listIssuesAfter(ctx, store, prefix, "")
+}
+
+// listIssuesAfter is like listIssues, but starts after the key startAfter.
+func listIssuesAfter(ctx context.Context, store Storage, prefix, startAfter string) iter.Seq2[StoredIssue, error] {
+	return func(yield func(StoredIssue, error) bool) {
+		for info, err := range store.List(ctx, prefix, startAfter) {
+			if err != nil {
+				yield(StoredIssue{}, err)
+				return
+			}
+
+			issue, err := NewIssueFromKey(info.Key)
+			if err != nil {
+				continue
+			}
+
+			if !yield(StoredIssue{Issue: issue, Info: info}, nil) {
+				return
+			}
+		}
+	}
+}
+
+// IssueFilter selects a subset of stored issues.
+type IssueFilter struct {
+	// From and To are inclusive bounds. Zero values are unbounded.
+	From, To time.Time
+	Year     int
+	Month    time.Month
+	// Ext matches the file extension, including the leading dot.
+	Ext string
+}
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// ParseIssueFilter parses the `from`, `to`, `year`, `month` and `ext` query params.
+func ParseIssueFilter(q url.Values) (IssueFilter, error) {
+	var f IssueFilter
+	var err error
+
+	if v := q.Get("from"); v != "" {
+		if f.From, err = parseIssueDate(time.DateOnly, v); err != nil {
+			return f, fmt.Errorf("%w: from: %w", ErrInvalidFilter, err)
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if f.To, err = parseIssueDate(time.DateOnly, v); err != nil {
+			return f, fmt.Errorf("%w: to: %w", ErrInvalidFilter, err)
+		}
+	}
+	if v := q.Get("year"); v != "" {
+		if f.Year, err = strconv.Atoi(v); err != nil || f.Year < 1 || f.Year > 9999 {
+			return f, fmt.Errorf("%w: year must be between 1 and 9999", ErrInvalidFilter)
+		}
+	}
+	if v := q.Get("month"); v != "" {
+		m, err := strconv.Atoi(v)
+		if err != nil || m < 1 || m > 12 {
+			return f, fmt.Errorf("%w: month must be between 1 and 12", ErrInvalidFilter)
+		}
+		f.Month = time.Month(m)
+	}
+	if v := q.Get("ext"); v != "" {
+		f.Ext = "." + strings.TrimPrefix(v, ".")
+	}
+	return f, nil
+}
+
+// Prefix returns the narrowest storage key prefix that contains every matching issue.
+func (f IssueFilter) Prefix() string {
+	var prefix string
+	switch {
+	case f.Year != 0 && f.Month != 0:
+		prefix = fmt.Sprintf("%04d/%02d/", f.Year, f.Month)
+	case f.Year != 0:
+		prefix = fmt.Sprintf("%04d/", f.Year)
+	}
+
+	if !f.From.IsZero() && !f.To.IsZero() {
+		// Keys sort by date, so every key in the range shares the prefix of its bounds.
+		from, to := f.From.Format(issueKeyDateFormat), f.To.Format(issueKeyDateFormat)
+		n := 0
+		for n < len(from) && from[n] == to[n] {
+			n++
+		}
+		if n > len(prefix) {
+			prefix = from[:n]
+		}
+	}
+	return prefix
+}
+
+// StartAfter returns a storage key that every matching issue sorts after, or "" if unbounded.
+func (f IssueFilter) StartAfter() string {
+	if f.From.IsZero() {
+		return ""
+	}
+	// Keys on From have an extension, so they sort after the bare date.
+	return f.From.Format(issueKeyDateFormat)
+}
+
+// Match reports whether issue passes the filter.
+func (f IssueFilter) Match(issue *Issue) bool {
+	switch {
+	case !f.From.IsZero() && issue.Date.Before(f.From),
+		!f.To.IsZero() && issue.Date.After(f.To),
+		f.Year != 0 && issue.Date.Year() != f.Year,
+		f.Month != 0 && issue.Date.Month() != f.Month,
+		f.Ext != "" && !strings.EqualFold(issue.Ext, f.Ext):
+		return false
+	}
+	return true
+}
+
+const (
+	defaultIssuesLimit = 100
+	maxIssuesLimit     = 1000
+)
+
+// IssueResponse is the JSON representation of a stored issue.
+type IssueResponse struct {
+	Date         string    `json:"date"`
+	Ext          string    `json:"ext"`
+	Path         string    `json:"path"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified time.Time `json:"last_modified,omitzero"`
+}
+
+func NewIssueResponse(issue StoredIssue) IssueResponse {
+	return IssueResponse{
+		Date:         issue.Date.Format(time.DateOnly),
+		Ext:          issue.Ext,
+		Path:         "/" + issue.ShortPath(),
+		Size:         issue.Info.Size,
+		ETag:         issue.Info.ETag,
+		LastModified: issue.Info.LastModified,
+	}
+}
+
+// issuesHandler lists stored issues as JSON in ascending date order.
+//
+// Results can be narrowed with the params accepted by ParseIssueFilter and are paginated
+// with `limit` and the opaque `cursor` returned as `next_cursor` in the previous page.
+//
+// Each page starts listing after the cursor or `from`, and stops after `to`.
+// With FSStorage, `etag` is only included for files whose hash is already cached, so it may
+// be missing for issues that have not been read or written since the server started.
+func issuesHandler(store Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		filter, err := ParseIssueFilter(q)
+		if err != nil {
+			handleHTTPError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		limit := defaultIssuesLimit
+		if v := q.Get("limit"); v != "" {
+			if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxIssuesLimit {
+				handleHTTPError(w, fmt.Sprintf("limit must be between 1 and %d", maxIssuesLimit), http.StatusBadRequest)
+				return
+			}
+		}
+
+		var after string
+		if v := q.Get("cursor"); v != "" {
+			b, err := base64.RawURLEncoding.DecodeString(v)
+			if err != nil {
+				handleHTTPError(w, "invalid cursor", http.StatusBadRequest)
+				return
+			}
+			after = string(b)
+		}
+
+		page := struct {
+			Issues     []IssueResponse `json:"issues"`
+			NextCursor string          `json:"next_cursor,omitempty"`
+		}{
+			Issues: make([]IssueResponse, 0, min(limit, defaultIssuesLimit)),
+		}
+
+		var lastKey string
+		for issue, err := range listIssuesAfter(r.Context(), store, filter.Prefix(), max(after, filter.StartAfter())) {
+			if err != nil {
+				handleStorageError(w, err)
+				return
+			}
+			if !filter.To.IsZero() && issue.Date.After(filter.To) {
+				break
+			}
+			if !filter.Match(issue.Issue) {
+				continue
+			}
+
+			if len(page.Issues) == limit {
+				page.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(lastKey))
+				break
+			}
+			page.Issues = append(page.Issues, NewIssueResponse(issue))
+			lastKey = issue.Info.Key
+		}
+
+		writeJSON(w, http.StatusOK, page)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type issuesPage struct {
+	Issues     []IssueResponse `json:"issues"`
+	NextCursor string          `json:"next_cursor"`
+}
+
+func getIssues(t *testing.T, handler http.HandlerFunc, q url.Values) (int, issuesPage) {
+	t.Helper()
+	r := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/api/issues?"+q.Encode(), nil)
+	w := httptest.NewRecorder()
+	handler(w, r)
+
+	var page issuesPage
+	if w.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+	}
+	return w.Code, page
+}
+
+func paths(page issuesPage) []string {
+	paths := make([]string, 0, len(page.Issues))
+	for _, issue := range page.Issues {
+		paths = append(paths, issue.Path)
+	}
+	return paths
+}
+
+func TestIssuesHandler(t *testing.T) {
+	store := newMemStorage(
+		"2025/12/31.pdf",
+		"2026/07/31.pdf",
+		"2026/08/04.pdf",
+		"2026/08/05.epub",
+		"2026/08/05.pdf",
+		"backups/2026/08/05.20260805T120000Z.pdf",
+	)
+	handler := issuesHandler(store)
+
+	tests := []struct {
+		name     string
+		query    url.Values
+		wantCode int
+		want     []string
+	}{
+		{"all", nil, http.StatusOK, []string{
+			"/2025-12-31.pdf", "/2026-07-31.pdf", "/2026-08-04.pdf", "/2026-08-05.epub", "/2026-08-05.pdf",
+		}},
+		{"year", url.Values{"year": {"2025"}}, http.StatusOK, []string{"/2025-12-31.pdf"}},
+		{"month", url.Values{"month": {"8"}}, http.StatusOK, []string{
+			"/2026-08-04.pdf", "/2026-08-05.epub", "/2026-08-05.pdf",
+		}},
+		{"year month", url.Values{"year": {"2026"}, "month": {"7"}}, http.StatusOK, []string{"/2026-07-31.pdf"}},
+		{"range", url.Values{"from": {"2026-07-31"}, "to": {"2026-08-04"}}, http.StatusOK, []string{
+			"/2026-07-31.pdf", "/2026-08-04.pdf",
+		}},
+		{"ext", url.Values{"ext": {"epub"}}, http.StatusOK, []string{"/2026-08-05.epub"}},
+		{"no matches", url.Values{"year": {"2020"}}, http.StatusOK, []string{}},
+		{"bad date", url.Values{"from": {"yesterday"}}, http.StatusBadRequest, nil},
+		{"bad month", url.Values{"month": {"13"}}, http.StatusBadRequest, nil},
+		{"bad limit", url.Values{"limit": {"0"}}, http.StatusBadRequest, nil},
+		{"bad cursor", url.Values{"cursor": {"!"}}, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, page := getIssues(t, handler, tt.query)
+			assert.Equal(t, tt.wantCode, code)
+			if tt.want != nil {
+				assert.Equal(t, tt.want, paths(page))
+			}
+		})
+	}
+
+	t.Run("metadata", func(t *testing.T) {
+		_, page := getIssues(t, handler, url.Values{"year": {"2025"}})
+		require.Len(t, page.Issues, 1)
+		got := page.Issues[0]
+		assert.Equal(t, "2025-12-31", got.Date)
+		assert.Equal(t, ".pdf", got.Ext)
+		assert.EqualValues(t, len("%PDF-1.4 2025/12/31.pdf"), got.Size)
+		assert.NotEmpty(t, got.ETag)
+		assert.False(t, got.LastModified.IsZero())
+	})
+}
+
+// listRecorder records the startAfter of every List.
+type listRecorder struct {
+	*memStorage
+	mu         sync.Mutex
+	startAfter []string
+}
+
+func (l *listRecorder) List(ctx context.Context, prefix, startAfter string) iter.Seq2[ObjectInfo, error] {
+	l.mu.Lock()
+	l.startAfter = append(l.startAfter, startAfter)
+	l.mu.Unlock()
+	return l.memStorage.List(ctx, prefix, startAfter)
+}
+
+func TestIssueFilter_Prefix(t *testing.T) {
+	date := func(s string) time.Time {
+		d, err := time.Parse(time.DateOnly, s)
+		require.NoError(t, err)
+		return d
+	}
+	tests := []struct {
+		name           string
+		filter         IssueFilter
+		wantPrefix     string
+		wantStartAfter string
+	}{
+		{"none", IssueFilter{}, "", ""},
+		{"year", IssueFilter{Year: 2026}, "2026/", ""},
+		{"year month", IssueFilter{Year: 2026, Month: 8}, "2026/08/", ""},
+		{"month only", IssueFilter{Month: 8}, "", ""},
+		{"from", IssueFilter{From: date("2026-08-03")}, "", "2026/08/03"},
+		{"same month", IssueFilter{From: date("2026-08-03"), To: date("2026-08-31")}, "2026/08/", "2026/08/03"},
+		{"same day", IssueFilter{From: date("2026-08-05"), To: date("2026-08-05")}, "2026/08/05", "2026/08/05"},
+		{"across years", IssueFilter{From: date("2025-12-31"), To: date("2026-01-01")}, "202", "2025/12/31"},
+		{"range within year", IssueFilter{Year: 2026, From: date("2026-08-01"), To: date("2026-08-09")}, "2026/08/0", "2026/08/01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantPrefix, tt.filter.Prefix())
+			assert.Equal(t, tt.wantStartAfter, tt.filter.StartAfter())
+		})
+	}
+}
+
+func TestIssuesHandler_pagination(t *testing.T) {
+	store := &listRecorder{
+		memStorage: newMemStorage("2026/08/01.pdf", "2026/08/02.pdf", "2026/08/03.pdf", "2026/08/04.pdf", "2026/08/05.pdf"),
+	}
+	handler := issuesHandler(store)
+
+	var got []string
+	q := url.Values{"limit": {"2"}}
+	for range 5 {
+		code, page := getIssues(t, handler, q)
+		require.Equal(t, http.StatusOK, code)
+		got = append(got, paths(page)...)
+		if page.NextCursor == "" {
+			break
+		}
+		q.Set("cursor", page.NextCursor)
+	}
+
+	assert.Equal(t, []string{
+		"/2026-08-01.pdf", "/2026-08-02.pdf", "/2026-08-03.pdf", "/2026-08-04.pdf", "/2026-08-05.pdf",
+	}, got)
+	assert.Equal(t, []string{"", "2026/08/02.pdf", "2026/08/04.pdf"}, store.startAfter, "pages should start listing at the cursor")
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type JobState string
+
+const (
+	JobQueued      JobState = "queued"
+	JobDownloading JobState = "downloading"
+	JobStoring     JobState = "storing"
+	JobDone        JobState = "done"
+	JobFailed      JobState = "failed"
+)
+
+var (
+	ErrQueueFull    = errors.New("upload queue is full")
+	ErrQueueClosed  = errors.New("upload queue is shutting down")
+	ErrJobAbandoned = errors.New("upload job abandoned during shutdown")
+)
+
+// NewJobQueue returns a JobQueue which runs uploads with fetcher.
+func NewJobQueue(conf *Config, fetcher *Fetcher) *JobQueue {
+	return &JobQueue{
+		conf:    conf,
+		fetcher: fetcher,
+		queue:   make(chan *Job, conf.UploadQueueSize),
+		jobs:    make(map[string]*Job),
+		stopped: make(chan struct{}),
+	}
+}
+
+// JobQueue runs uploads in the background on a pool of workers.
+type JobQueue struct {
+	conf    *Config
+	fetcher *Fetcher
+	queue   chan *Job
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+	// closed is set by Shutdown, after which no more jobs are accepted.
+	closed bool
+	// cancel stops the workers started by Run, and stopped is closed once they have returned.
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+// Job is a queued upload.
+type Job struct {
+	ID      string
+	URL     *url.URL
+	Options FetchOptions
+	Created time.Time
+
+	// link is the span that enqueued the job. Jobs outlive the request, so their
+	// spans start a new trace linked to it.
+	link  trace.Link
+	bytes atomic.Int64
+
+	mu      sync.Mutex
+	state   JobState
+	size    int64
+	issue   *Issue
+	err     error
+	updated time.Time
+}
+
+// Enqueue queues an upload of u, returning ErrQueueFull if no more jobs can be accepted,
+// or ErrQueueClosed after Shutdown.
+func (q *JobQueue) Enqueue(ctx context.Context, u *url.URL, opts FetchOptions) (*Job, error) {
+	now := time.Now()
+	job := &Job{
+		ID:      rand.Text(),
+		URL:     u,
+		Options: opts,
+		Created: now,
+		link:    trace.LinkFromContext(ctx),
+		state:   JobQueued,
+		size:    -1,
+		updated: now,
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil, ErrQueueClosed
+	}
+	select {
+	case q.queue <- job:
+		q.jobs[job.ID] = job
+		return job, nil
+	default:
+		return nil, ErrQueueFull
+	}
+}
+
+// Get returns the job with the given ID.
+func (q *JobQueue) Get(id string) (*Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	return job, ok
+}
+
+// Run starts conf.UploadWorkers workers and blocks until ctx is canceled, or until
+// Shutdown has been called and every queued job has finished.
+func (q *JobQueue) Run(ctx context.Context) {
+	defer close(q.stopped)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	q.mu.Lock()
+	q.cancel = cancel
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for range max(q.conf.UploadWorkers, 1) {
+		wg.Go(func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case job, ok := <-q.queue:
+					if !ok {
+						return
+					}
+					q.run(ctx, job)
+				}
+			}
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			q.abandon()
+			return
+		case <-ticker.C:
+			q.prune()
+		}
+	}
+}
+
+// Shutdown stops accepting jobs and waits for queued and running jobs to finish.
+// If ctx is done first, running jobs are canceled, and every unfinished job is marked failed.
+func (q *JobQueue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.queue)
+	}
+	q.mu.Unlock()
+
+	select {
+	case <-q.stopped:
+		return nil
+	case <-ctx.Done():
+	}
+
+	q.mu.Lock()
+	cancel := q.cancel
+	q.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		<-q.stopped
+	} else {
+		// Run was never started
+		q.abandon()
+	}
+	return ctx.Err()
+}
+
+// abandon marks jobs that are still queued as failed.
+func (q *JobQueue) abandon() {
+	for {
+		select {
+		case job, ok := <-q.queue:
+			if !ok {
+				return
+			}
+			job.finish(nil, ErrJobAbandoned)
+		default:
+			return
+		}
+	}
+}
+
+func (q *JobQueue) run(ctx context.Context, job *Job) {
+	ctx, span := tracer.Start(ctx, "JobQueue.run",
+		trace.WithNewRoot(),
+		trace.WithLinks(job.link),
+		trace.WithAttributes(attribute.String("job.id", job.ID)),
+	)
+	defer span.End()
+
+	if q.conf.JobTimeout > 0 {
+		// Keep a stalled upstream from holding a worker until shutdown.
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, q.conf.JobTimeout)
+		defer cancel()
+	}
+
+	job.setState(JobDownloading)
+
+	opts := job.Options
+	opts.Transferred = &job.bytes
+	opts.OnStore = func(size int64) {
+		job.mu.Lock()
+		job.size = size
+		job.mu.Unlock()
+		job.setState(JobStoring)
+	}
+
+	issue, err := q.fetcher.Fetch(ctx, job.URL, opts)
+	if err != nil {
+		slog.Error("Upload job failed", "id", job.ID, "url", job.URL.String(), "error", err)
+	}
+	job.finish(issue, err)
+}
+
+// prune forgets finished jobs older than conf.JobRetention.
+func (q *JobQueue) prune() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for id, job := range q.jobs {
+		job.mu.Lock()
+		expired := (job.state == JobDone || job.state == JobFailed) && time.Since(job.updated) > q.conf.JobRetention
+		job.mu.Unlock()
+		if expired {
+			delete(q.jobs, id)
+		}
+	}
+}
+
+func (j *Job) setState(state JobState) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.state = state
+	j.updated = time.Now()
+}
+
+func (j *Job) finish(issue *Issue, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.issue, j.err = issue, err
+	if err != nil {
+		j.state = JobFailed
+	} else {
+		j.state = JobDone
+	}
+	j.updated = time.Now()
+}
+
+// JobStatus is the JSON representation of a Job.
+type JobStatus struct {
+	ID      string    `json:"id"`
+	State   JobState  `json:"state"`
+	URL     string    `json:"url"`
+	Bytes   int64     `json:"bytes"`
+	Size    int64     `json:"size,omitempty"`
+	Path    string    `json:"path,omitempty"`
+	Error   string    `json:"error,omitempty"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+// Status returns a snapshot of the job.
+func (j *Job) Status() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	status := JobStatus{
+		ID:      j.ID,
+		State:   j.state,
+		URL:     j.URL.String(),
+		Bytes:   j.bytes.Load(),
+		Created: j.Created,
+		Updated: j.updated,
+	}
+	if j.size >= 0 {
+		status.Size = j.size
+	}
+	if j.issue != nil {
+		status.Path = "/" + j.issue.ShortPath()
+	}
+	if j.err != nil {
+		status.Error = j.err.Error()
+	}
+	return status
+}
+
+// jobHandler reports the status of the job in the `id` URL param.
+func jobHandler(conf *Config, jobs *JobQueue) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(conf, r) {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		job, ok := jobs.Get(chi.URLParam(r, "id"))
+		if !ok {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, job.Status())
+	}
+}
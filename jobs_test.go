@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobQueue(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+
+	upstream := newUpstream(t)
+	store := newMemStorage()
+	conf := newTestConfig()
+	conf.UploadWorkers = 1
+	conf.UploadQueueSize = 1
+	conf.JobRetention = time.Hour
+	fetcher := NewFetcher(conf, store)
+	jobs := NewJobQueue(conf, fetcher)
+	upload := uploadHandler(conf, fetcher, jobs)
+	status := jobHandler(conf, jobs)
+
+	q := url.Values{"url": {upstream + paperPath}, "async": {"true"}}
+	r := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/api/upload?"+q.Encode(), nil)
+	r.Header.Set("Authorization", authKey)
+	w := httptest.NewRecorder()
+	upload(w, r)
+
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var queued JobStatus
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &queued))
+	assert.Equal(t, JobQueued, queued.State)
+	assert.Equal(t, "/api/jobs/"+queued.ID, w.Header().Get("Location"))
+
+	// The queue only holds one job, and no workers are running yet.
+	w = httptest.NewRecorder()
+	upload(w, r)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	go jobs.Run(t.Context())
+
+	var got JobStatus
+	require.Eventually(t, func() bool {
+		r := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/api/jobs/"+queued.ID, nil)
+		r = withURLParam(r, "id", queued.ID)
+		r.Header.Set("Authorization", authKey)
+		w := httptest.NewRecorder()
+		status(w, r)
+		if w.Code != http.StatusOK {
+			return false
+		}
+		got = JobStatus{}
+		return json.Unmarshal(w.Body.Bytes(), &got) == nil && got.State == JobDone
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, "/2026-08-05.pdf", got.Path)
+	assert.EqualValues(t, len("%PDF-1.4 fake"), got.Bytes)
+	assert.Empty(t, got.Error)
+	assert.Equal(t, []string{issueKey}, store.Keys())
+}
+
+func TestJobQueue_Shutdown(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+
+	upstream, err := url.Parse(newUpstream(t) + paperPath)
+	require.NoError(t, err)
+
+	t.Run("drains queued jobs", func(t *testing.T) {
+		store := newMemStorage()
+		conf := newTestConfig()
+		conf.UploadWorkers = 1
+		conf.UploadQueueSize = 1
+		jobs := NewJobQueue(conf, NewFetcher(conf, store))
+
+		job, err := jobs.Enqueue(t.Context(), upstream, FetchOptions{})
+		require.NoError(t, err)
+		go jobs.Run(t.Context())
+		require.NoError(t, jobs.Shutdown(t.Context()))
+
+		assert.Equal(t, JobDone, job.Status().State)
+		assert.Equal(t, []string{issueKey}, store.Keys())
+		_, err = jobs.Enqueue(t.Context(), upstream, FetchOptions{})
+		assert.ErrorIs(t, err, ErrQueueClosed)
+	})
+
+	t.Run("fails abandoned jobs", func(t *testing.T) {
+		store := newMemStorage()
+		conf := newTestConfig()
+		conf.UploadQueueSize = 1
+		jobs := NewJobQueue(conf, NewFetcher(conf, store))
+
+		job, err := jobs.Enqueue(t.Context(), upstream, FetchOptions{})
+		require.NoError(t, err)
+		// No workers are running, so nothing is drained before the deadline.
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, jobs.Shutdown(ctx), context.DeadlineExceeded)
+
+		status := job.Status()
+		assert.Equal(t, JobFailed, status.State)
+		assert.Equal(t, ErrJobAbandoned.Error(), status.Error)
+		assert.Empty(t, store.Keys())
+	})
+}
+
+func TestJobQueue_timeout(t *testing.T) {
+	// The upstream accepts the request and never responds.
+	upstream := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(upstream.Close)
+	u, err := url.Parse(upstream.URL + "/files/a1b2-issue-8-5-2026.pdf")
+	require.NoError(t, err)
+
+	conf := newTestConfig()
+	conf.UploadQueueSize = 1
+	conf.JobTimeout = 50 * time.Millisecond
+	jobs := NewJobQueue(conf, NewFetcher(conf, newMemStorage()))
+	go jobs.Run(t.Context())
+
+	job, err := jobs.Enqueue(t.Context(), u, FetchOptions{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return job.Status().State == JobFailed
+	}, time.Second, 10*time.Millisecond)
+	assert.Contains(t, job.Status().Error, context.DeadlineExceeded.Error())
+}
+
+func TestJobHandler(t *testing.T) {
+	conf := &Config{UploadAuthKey: authKey, UploadQueueSize: 1}
+	handler := jobHandler(conf, NewJobQueue(conf, NewFetcher(conf, newMemStorage())))
+
+	r := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/api/jobs/missing", nil)
+	r = withURLParam(r, "id", "missing")
+	w := httptest.NewRecorder()
+	handler(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	r.Header.Set("Authorization", authKey)
+	w = httptest.NewRecorder()
+	handler(w, r)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -1,68 +1,264 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
 	"sync/atomic"
 	"time"
-
-	"github.com/minio/minio-go/v7"
 )
 
 //nolint:gochecknoglobals
-var latest atomic.Pointer[Issue]
+var latest latestIssues
+
+// latestIssues tracks the newest stored issue of each format.
+// The map is replaced on every change, so readers never block.
+type latestIssues struct {
+	byExt atomic.Pointer[map[string]*Issue]
+	// err is the error of the last failed refreshLatest, cleared once one succeeds.
+	err atomic.Pointer[error]
+}
+
+// Err returns the error of the last failed attempt to find the latest issues, if the
+// most recent attempt failed.
+func (l *latestIssues) Err() error {
+	if err := l.err.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// All returns the latest issue of each format, keyed by extension. The map must not be modified.
+func (l *latestIssues) All() map[string]*Issue {
+	if m := l.byExt.Load(); m != nil {
+		return *m
+	}
+	return nil
+}
+
+// Get returns the latest issue with the given extension, or nil if there is none.
+func (l *latestIssues) Get(ext string) *Issue {
+	return l.All()[ext]
+}
+
+// Load returns the newest issue in any format, or nil if there is none.
+// If several formats share the newest date, the default format is preferred.
+func (l *latestIssues) Load() *Issue {
+	return l.newest([]string{defaultExt}, false)
+}
+
+// Preferred returns the newest issue with one of the given extensions, or nil if there is none.
+// If several formats share the newest date, the one listed first wins.
+// An empty list matches any extension, like Load.
+func (l *latestIssues) Preferred(formats []string) *Issue {
+	return l.newest(formats, len(formats) != 0)
+}
+
+// newest returns the newest issue, choosing between formats with preferIssue.
+// If only is set, other extensions are skipped.
+func (l *latestIssues) newest(formats []string, only bool) *Issue {
+	var best *Issue
+	for _, issue := range l.All() {
+		if only && !slices.Contains(formats, issue.Ext) {
+			continue
+		}
+		if best == nil || preferIssue(issue, best, formats) {
+			best = issue
+		}
+	}
+	return best
+}
 
-// storeLatest sets issue as the latest issue, unless a newer one is already stored.
+// preferIssue reports whether a should be chosen over b. Newer issues win, then extensions
+// listed earlier in formats, then extensions in lexical order so that the choice is deterministic.
+func preferIssue(a, b *Issue, formats []string) bool {
+	if !a.Date.Equal(b.Date) {
+		return a.Date.After(b.Date)
+	}
+
+	rank := func(ext string) int {
+		if i := slices.Index(formats, ext); i != -1 {
+			return i
+		}
+		return len(formats)
+	}
+	if c := cmp.Compare(rank(a.Ext), rank(b.Ext)); c != 0 {
+		return c < 0
+	}
+	return a.Ext < b.Ext
+}
+
+// Store replaces every tracked format with issue and clears Err. A nil issue clears them.
+func (l *latestIssues) Store(issue *Issue) {
+	l.err.Store(nil)
+	if issue == nil {
+		l.byExt.Store(nil)
+		return
+	}
+	l.byExt.Store(&map[string]*Issue{issue.Ext: issue})
+}
+
+// storeLatest sets issue as the latest issue of its format, unless a newer one is already stored.
 func storeLatest(issue *Issue) {
 	for {
-		curr := latest.Load()
-		if curr != nil && !issue.Date.After(curr.Date) {
+		ptr := latest.byExt.Load()
+		var curr map[string]*Issue
+		if ptr != nil {
+			curr = *ptr
+		}
+		if prev := curr[issue.Ext]; prev != nil && !issue.Date.After(prev.Date) {
 			return
 		}
-		if latest.CompareAndSwap(curr, issue) {
+
+		next := maps.Clone(curr)
+		if next == nil {
+			next = make(map[string]*Issue, 1)
+		}
+		next[issue.Ext] = issue
+		if latest.byExt.CompareAndSwap(ptr, &next) {
 			return
 		}
 	}
 }
 
-func findLatest(ctx context.Context, conf *Config, s3 *minio.Client) (*Issue, error) {
-	// Fast path for today
-	now := time.Now()
-	if _, err := s3.StatObject(ctx, conf.S3Bucket, now.Format("2006/01/02.pdf"),
-		minio.StatObjectOptions{},
-	); err == nil {
-		return NewIssueFromDate(now, ".pdf"), nil
+// trackLatest sets issue in m if it is newer than the latest issue of its format.
+func trackLatest(m map[string]*Issue, issue *Issue) {
+	if curr := m[issue.Ext]; curr == nil || issue.Date.After(curr.Date) {
+		m[issue.Ext] = issue
 	}
+}
+
+// findLatest returns the latest stored issue of each format, keyed by extension.
+func findLatest(ctx context.Context, store Storage) (map[string]*Issue, error) {
+	idx, err := loadIndex(ctx, store)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			slog.Warn("Failed to load index", "error", err)
+		}
 
-	// Fast path for yesterday
-	now = now.AddDate(0, 0, -1)
-	if _, err := s3.StatObject(ctx, conf.S3Bucket, now.Format("2006/01/02.pdf"),
-		minio.StatObjectOptions{},
-	); err == nil {
-		return NewIssueFromDate(now, ".pdf"), nil
+		// Slow path
+		found := make(map[string]*Issue)
+		for issue, err := range listIssues(ctx, store, "20") {
+			if err != nil {
+				return nil, err
+			}
+			trackLatest(found, issue.Issue)
+		}
+		return found, nil
 	}
 
-	// Slow path
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+	found := idx.Latest()
 
-	var latest time.Time
-	for item := range s3.ListObjectsIter(ctx, conf.S3Bucket, minio.ListObjectsOptions{
-		Prefix:    "20",
-		Recursive: true,
-	}) {
-		if item.Err != nil {
-			return nil, item.Err
+	// The index can miss issues stored concurrently by other replicas, so check
+	// today and yesterday directly. Dates are truncated like parsed issue dates so
+	// that repeated refreshes compare equal.
+	today := issueToday()
+	exts := slices.Sorted(maps.Keys(found))
+	if !slices.Contains(exts, defaultExt) {
+		exts = append(exts, defaultExt)
+	}
+	for _, ext := range exts {
+		for _, date := range []time.Time{today, today.AddDate(0, 0, -1)} {
+			if curr := found[ext]; curr != nil && !date.After(curr.Date) {
+				break
+			}
+			issue := NewIssueFromDate(date, ext)
+			if _, err := store.Stat(ctx, issue.FullPath()); err == nil {
+				found[ext] = issue
+				break
+			}
 		}
+	}
+	return found, nil
+}
 
-		d, err := time.Parse("2006/01/02.pdf", item.Key)
-		if err != nil {
-			continue
+// refreshLatest runs findLatest and stores every format that is newer than the one already known.
+// It returns the latest issue of each format afterwards.
+func refreshLatest(ctx context.Context, store Storage) (map[string]*Issue, error) {
+	found, err := findLatest(ctx, store)
+	if err != nil {
+		latest.err.Store(&err)
+		return nil, err
+	}
+	latest.err.Store(nil)
+
+	prev := latest.All()
+	for _, issue := range found {
+		storeLatest(issue)
+	}
+	curr := latest.All()
+	for ext, issue := range curr {
+		if issue != prev[ext] {
+			slog.Info("Refreshed latest file", "issue", issue, "previous", prev[ext])
 		}
+	}
+	return curr, nil
+}
 
-		if d.After(latest) {
-			latest = d
+// refreshLatestEvery calls refreshLatest every interval until ctx is canceled,
+// so that issues stored by other replicas are picked up.
+func refreshLatestEvery(ctx context.Context, store Storage, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := refreshLatest(ctx, store); err != nil && ctx.Err() == nil {
+				slog.Error("Failed to refresh latest file", "error", err)
+			}
 		}
 	}
+}
 
-	return NewIssueFromDate(latest, ".pdf"), nil
+// discoverLatest calls refreshLatest every interval until an issue is known or ctx is canceled.
+// It lets the server start while storage is empty or unreachable.
+func discoverLatest(ctx context.Context, store Storage, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for latest.Load() == nil {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := refreshLatest(ctx, store); err != nil && ctx.Err() == nil {
+				slog.Warn("Failed to find latest file", "retry", interval, "error", err)
+			}
+		}
+	}
+}
+
+// refreshLatestHandler refreshes the latest issue on demand and responds with its path.
+func refreshLatestHandler(conf *Config, store Storage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(conf, r) {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		found, err := refreshLatest(r.Context(), store)
+		if err != nil {
+			handleStorageError(w, err)
+			return
+		}
+
+		var resp struct {
+			Latest  string            `json:"latest,omitempty"`
+			Formats map[string]string `json:"formats"`
+		}
+		resp.Formats = make(map[string]string, len(found))
+		for ext, issue := range found {
+			resp.Formats[ext] = "/" + issue.ShortPath()
+		}
+		if issue := latest.Preferred(conf.LatestFormats); issue != nil {
+			resp.Latest = "/" + issue.ShortPath()
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
 }
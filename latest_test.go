@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindLatest(t *testing.T) {
+	now := time.Now()
+	today := now.Format("2006/01/02.pdf")
+	yesterday := now.AddDate(0, 0, -1).Format("2006/01/02.pdf")
+
+	tests := []struct {
+		name string
+		keys []string
+		want map[string]string
+	}{
+		{"today", []string{"2025/01/02.pdf", today}, map[string]string{".pdf": now.Format(time.DateOnly) + ".pdf"}},
+		{"yesterday", []string{"2025/01/02.pdf", yesterday}, map[string]string{
+			".pdf": now.AddDate(0, 0, -1).Format(time.DateOnly) + ".pdf",
+		}},
+		{"listing", []string{"2025/01/02.pdf", "2024/12/31.pdf", "2025/01/01.pdf"}, map[string]string{".pdf": "2025-01-02.pdf"}},
+		{"per format", []string{"2025/01/02.pdf", "2025/01/03.epub", "2025/01/01.epub"}, map[string]string{
+			".pdf":  "2025-01-02.pdf",
+			".epub": "2025-01-03.epub",
+		}},
+		{"ignores other keys", []string{"2025/01/02.pdf", "backups/2025/01/03.pdf", "index.html"}, map[string]string{
+			".pdf": "2025-01-02.pdf",
+		}},
+		{"empty", nil, map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := findLatest(t.Context(), newMemStorage(tt.keys...))
+			require.NoError(t, err)
+			got := make(map[string]string, len(found))
+			for ext, issue := range found {
+				got[ext] = issue.ShortPath()
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLatestIssues_Preferred(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+	pdf := NewIssueFromDate(time.Date(2026, 8, 5, 0, 0, 0, 0, time.UTC), ".pdf")
+	epub := NewIssueFromDate(time.Date(2026, 8, 5, 0, 0, 0, 0, time.UTC), ".epub")
+	newerEpub := NewIssueFromDate(time.Date(2026, 8, 6, 0, 0, 0, 0, time.UTC), ".epub")
+
+	latest.Store(nil)
+	assert.Nil(t, latest.Preferred([]string{".pdf"}))
+
+	storeLatest(pdf)
+	storeLatest(epub)
+	assert.Equal(t, pdf, latest.Get(".pdf"))
+	assert.Equal(t, epub, latest.Get(".epub"))
+	assert.Equal(t, pdf, latest.Load(), "should prefer the default format on the same date")
+	assert.Equal(t, pdf, latest.Preferred([]string{".pdf", ".epub"}))
+	assert.Equal(t, epub, latest.Preferred([]string{".epub", ".pdf"}))
+
+	storeLatest(newerEpub)
+	assert.Equal(t, pdf, latest.Get(".pdf"), "other formats should be kept")
+	assert.Equal(t, newerEpub, latest.Load())
+	assert.Equal(t, newerEpub, latest.Preferred([]string{".pdf", ".epub"}), "should prefer newer issues over format order")
+	assert.Equal(t, pdf, latest.Preferred([]string{".pdf"}))
+	assert.Equal(t, newerEpub, latest.Preferred(nil))
+	assert.Nil(t, latest.Preferred([]string{".mobi"}))
+}
+
+func TestRefreshLatest(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+	store := newMemStorage("2026/08/04.pdf")
+
+	got, err := refreshLatest(t.Context(), store)
+	require.NoError(t, err)
+	assert.Equal(t, "2026-08-04.pdf", got[".pdf"].ShortPath())
+
+	// Another replica stores a newer issue
+	store.add("2026/08/05.pdf", "%PDF-1.4")
+	got, err = refreshLatest(t.Context(), store)
+	require.NoError(t, err)
+	assert.Equal(t, "2026-08-05.pdf", got[".pdf"].ShortPath())
+
+	// A newer issue stored by this process is kept
+	storeLatest(NewIssueFromDate(time.Date(2026, 8, 6, 0, 0, 0, 0, time.UTC), ".pdf"))
+	got, err = refreshLatest(t.Context(), store)
+	require.NoError(t, err)
+	assert.Equal(t, "2026-08-06.pdf", got[".pdf"].ShortPath())
+
+	// Other formats are tracked separately
+	store.add("2026/08/05.epub", "epub")
+	got, err = refreshLatest(t.Context(), store)
+	require.NoError(t, err)
+	assert.Equal(t, "2026-08-06.pdf", got[".pdf"].ShortPath())
+	assert.Equal(t, "2026-08-05.epub", got[".epub"].ShortPath())
+}
+
+func TestRefreshLatestEvery(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+	store := newMemStorage("2026/08/04.pdf")
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		refreshLatestEvery(ctx, store, time.Millisecond)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		issue := latest.Load()
+		return issue != nil && issue.ShortPath() == "2026-08-04.pdf"
+	}, time.Second, time.Millisecond)
+	cancel()
+	<-done
+}
+
+func TestDiscoverLatest(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+	latest.Store(nil)
+
+	store := &brokenStorage{memStorage: newMemStorage()}
+	err := errors.New("connection refused")
+	store.err.Store(&err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		discoverLatest(ctx, store, time.Millisecond)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return latest.Err() != nil
+	}, time.Second, time.Millisecond)
+
+	// Storage recovers, but is still empty
+	store.err.Store(nil)
+	assert.Eventually(t, func() bool {
+		return latest.Err() == nil
+	}, time.Second, time.Millisecond)
+	assert.Nil(t, latest.Load())
+
+	store.add("2026/08/05.pdf", "%PDF-1.4")
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("discoverLatest should return once an issue is found")
+	}
+	assert.Equal(t, "2026-08-05.pdf", latest.Load().ShortPath())
+}
+
+func TestRefreshLatestHandler(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+	conf := newTestConfig()
+	conf.LatestFormats = []string{".pdf", ".epub"}
+	handler := refreshLatestHandler(conf, newMemStorage("2026/08/05.pdf", "2026/08/05.epub"))
+
+	r := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/api/latest/refresh", nil)
+	w := httptest.NewRecorder()
+	handler(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Nil(t, latest.Load())
+
+	r.Header.Set("Authorization", authKey)
+	w = httptest.NewRecorder()
+	handler(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"latest":"/2026-08-05.pdf","formats":{".pdf":"/2026-08-05.pdf",".epub":"/2026-08-05.epub"}}`,
+		w.Body.String())
+}
+
+func TestFindLatest_timezone(t *testing.T) {
+	// A zone where the edition date is likely to differ from UTC
+	setIssueLocation(t, "Pacific/Kiritimati")
+	today := issueToday()
+	store := newMemStorage("2025/01/02.pdf", today.Format("2006/01/02.pdf"))
+
+	got, err := findLatest(t.Context(), store)
+	require.NoError(t, err)
+	assert.True(t, today.Equal(got[".pdf"].Date))
+}
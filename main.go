@@ -2,27 +2,55 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
-	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/httprate"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
-	if err := run(); err != nil {
+	var err error
+	if len(os.Args) > 1 && os.Args[1] == "rebuild-index" {
+		err = runRebuildIndex()
+	} else {
+		err = run()
+	}
+	if err != nil {
 		slog.Error(err.Error())
 		os.Exit(1)
 	}
 }
 
+// runRebuildIndex regenerates the issue index from a full listing of the configured storage.
+func runRebuildIndex() error {
+	conf, err := Load()
+	if err != nil {
+		return err
+	}
+	issueLocation = conf.PublicationTimezone
+
+	store, err := NewStorage(conf)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
+	defer cancel()
+
+	_, err = rebuildIndex(ctx, store)
+	return err
+}
+
 var ErrUpstream = errors.New("upstream error")
 
 func run() error {
@@ -30,48 +58,125 @@ func run() error {
 	if err != nil {
 		return err
 	}
+	issueLocation = conf.PublicationTimezone
+
+	shutdownTracing, err := setupTracing(context.Background(), conf)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("Failed to flush traces", "error", err)
+		}
+	}()
+
+	store, err := NewStorage(conf)
+	if err != nil {
+		return err
+	}
+
+	fetcher := NewFetcher(conf, store)
 
-	s3, err := NewS3(conf)
+	calendar, err := NewCalendar(conf)
 	if err != nil {
 		return err
 	}
+	if conf.MetricsEnabled {
+		registerCalendarMetrics(calendar)
+	}
+
+	var scheduler *Scheduler
+	if conf.FetchSchedule != "" {
+		if scheduler, err = NewScheduler(conf, fetcher, calendar); err != nil {
+			return err
+		}
+	}
 
 	r := chi.NewRouter()
 
+	// Probes and scrapes are served ahead of the rate limiter so that other traffic can't make them fail.
 	r.Use(middleware.Heartbeat("/ping"))
+	r.Use(serveAt("/readyz", NewReadiness(conf, store, scheduler).handler()))
+	if conf.MetricsEnabled {
+		r.Use(serveAt("/metrics", promhttp.Handler()))
+	}
 	if conf.TrustedProxies != nil {
 		r.Use(middleware.ClientIPFromXFF(conf.TrustedProxies...))
 	}
+	r.Use(tracingMiddleware)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
+	r.Use(metricsMiddleware)
 	r.Use(httprate.LimitBy(conf.LimitRequests, conf.LimitWindow, func(r *http.Request) (string, error) {
 		return middleware.GetClientIP(r.Context()), nil
-	}))
+	}, httprate.WithLimitHandler(rateLimitHandler)))
 
-	upload := uploadHandler(conf, s3)
+	jobs := NewJobQueue(conf, fetcher)
+	upload := uploadHandler(conf, fetcher, jobs)
 	r.Get("/api/upload", upload)
 	r.Post("/api/upload", upload)
+	r.Get("/api/jobs/{id}", jobHandler(conf, jobs))
+	r.Post("/api/latest/refresh", refreshLatestHandler(conf, store))
+
+	r.Get("/api/issues", issuesHandler(store))
+
+	archive, err := archiveHandler(store)
+	if err != nil {
+		return err
+	}
+	r.Get("/archive", archive)
+
+	feed := NewFeed(conf, store)
+	fetcher.OnSave(feed.Invalidate)
+	r.Get("/feed.atom", feed.atomHandler())
+	r.Get("/feed.rss", feed.rssHandler())
+	r.Mount("/opds", opdsRouter(conf, store, feed))
+
+	r.Get("/api/gaps", gapsHandler(store, calendar))
+	r.Post("/api/backfill", backfillHandler(conf, fetcher, calendar))
+	if scheduler != nil {
+		r.Get("/api/schedule", scheduler.statusHandler())
+	}
 
 	if conf.RedirectToLatest {
-		r.Get("/", redirectLatest())
+		redirect, err := redirectLatest(conf)
+		if err != nil {
+			return err
+		}
+		r.Get("/", redirect)
 	}
 
-	r.Get("/*", get(conf, s3))
+	r.Get("/*", get(conf, store))
 
-	server := &http.Server{
-		Addr:        conf.ListenAddress,
-		Handler:     r,
-		ReadTimeout: 5 * time.Second,
-	}
+	server := newServer(conf, r)
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
 	defer cancel()
 
-	if issue, err := findLatest(ctx, conf, s3); err == nil {
-		slog.Info("Found latest file", "issue", issue)
-		latest.Store(issue)
-	} else {
-		return fmt.Errorf("failed to find latest file: %w", err)
+	if err := ensureIndex(ctx, store); err != nil {
+		slog.Error("Failed to build index", "error", err)
+	}
+
+	// Start without a latest issue if storage is empty or unreachable.
+	// It is picked up by discoverLatest or the next upload.
+	if _, err := refreshLatest(ctx, store); err != nil {
+		slog.Error("Failed to find latest file", "error", err)
+	}
+	if latest.Load() == nil {
+		slog.Warn("Latest file unknown, starting in degraded mode", "retry", conf.LatestDiscoveryInterval)
+		go discoverLatest(ctx, store, conf.LatestDiscoveryInterval)
+	}
+
+	// Jobs have already been accepted, so they are drained after the server shuts down
+	// rather than canceled by the signal.
+	go jobs.Run(context.Background())
+	if conf.LatestRefreshInterval > 0 {
+		go refreshLatestEvery(ctx, store, conf.LatestRefreshInterval)
+	}
+	if scheduler != nil {
+		go scheduler.Run(ctx)
 	}
 
 	errCh := make(chan error, 1)
@@ -97,13 +202,32 @@ func run() error {
 		if err := server.Shutdown(ctx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
 			return err
 		}
+		if err := jobs.Shutdown(ctx); err != nil {
+			slog.Warn("Canceled unfinished upload jobs", "error", err)
+		}
 		return nil
 	case err := <-errCh:
 		return err
 	}
 }
 
+// newServer returns the HTTP server for handler.
+// Only headers have a read timeout, so that large uploads over slow links aren't cut off.
+func newServer(conf *Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              conf.ListenAddress,
+		Handler:           handler,
+		ReadHeaderTimeout: conf.ReadHeaderTimeout,
+	}
+}
+
 func handleHTTPError(w http.ResponseWriter, msg string, status int) {
 	slog.Error("Download failed", "error", msg, "status", status)
 	http.Error(w, msg, status)
 }
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"iter"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const metricsNamespace = "wsj_dl"
+
+//nolint:gochecknoglobals
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route and status code.",
+	}, []string{"method", "route", "code"})
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latencies by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+	servedBytes = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "served_bytes_total",
+		Help:      "Bytes of issue files served.",
+	})
+	rateLimited = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "rate_limited_total",
+		Help:      "HTTP requests rejected by the rate limiter.",
+	})
+
+	uploadAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "upload_attempts_total",
+		Help:      "Upload attempts by source.",
+	}, []string{"source"})
+	uploadSuccesses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "upload_successes_total",
+		Help:      "Successful uploads by source.",
+	}, []string{"source"})
+	uploadFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "upload_failures_total",
+		Help:      "Failed uploads by source and reason.",
+	}, []string{"source", "reason"})
+	upstreamResponses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "upstream_responses_total",
+		Help:      "Upstream download responses by status code.",
+	}, []string{"code"})
+
+	storageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "Storage operation latencies by backend and operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"backend", "op"})
+	storageErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "storage_errors_total",
+		Help:      "Failed storage operations by backend and operation. Missing keys are not counted.",
+	}, []string{"backend", "op"})
+
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "latest_issue_timestamp_seconds",
+		Help:      "Date of the latest issue as a Unix timestamp. 0 if unknown.",
+	}, func() float64 {
+		if issue := latest.Load(); issue != nil {
+			return float64(issue.Date.Unix())
+		}
+		return 0
+	})
+)
+
+// registerCalendarMetrics exposes whether the latest issue is overdue according to calendar,
+// so that alerts aren't raised on days without a paper. It must only be called once.
+func registerCalendarMetrics(calendar *Calendar) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "latest_issue_overdue",
+		Help:      "1 if the publication calendar expects a newer issue than the latest one, 0 otherwise.",
+	}, func() float64 {
+		if calendar.Overdue(latest.Load(), time.Now()) {
+			return 1
+		}
+		return 0
+	})
+}
+
+const (
+	uploadSourceURL  = "url"
+	uploadSourceFile = "file"
+)
+
+// metricsMiddleware records request counts and latencies by chi route pattern.
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := cmp.Or(routePattern(r), "unknown")
+		code := ww.Status()
+		if code == 0 {
+			code = http.StatusOK
+		}
+
+		httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(code)).Inc()
+		httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// routePattern returns the chi route pattern that matched r. It is only known after routing.
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return rctx.RoutePattern()
+	}
+	return ""
+}
+
+// rateLimitHandler responds to requests rejected by httprate.
+func rateLimitHandler(w http.ResponseWriter, _ *http.Request) {
+	rateLimited.Inc()
+	http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+}
+
+// observeUpload records the outcome of an upload.
+func observeUpload(source string, err error) {
+	uploadAttempts.WithLabelValues(source).Inc()
+	if err == nil {
+		uploadSuccesses.WithLabelValues(source).Inc()
+	} else {
+		uploadFailures.WithLabelValues(source, uploadFailureReason(err)).Inc()
+	}
+}
+
+// uploadFailureReason returns a metric label for the stage of Fetcher.Fetch that failed.
+func uploadFailureReason(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidURL):
+		return "invalid_url"
+	case errors.Is(err, ErrInvalidFilename):
+		return "invalid_filename"
+	case errors.Is(err, ErrExists):
+		return "exists"
+	case errors.Is(err, ErrNotPublished):
+		return "not_published"
+	case errors.Is(err, ErrInvalidContent):
+		return "invalid_content"
+	case errors.Is(err, ErrUpstream):
+		return "upstream"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	default:
+		return "error"
+	}
+}
+
+// instrumentedStorage records metrics and trace spans for every operation on a Storage.
+type instrumentedStorage struct {
+	Storage
+	backend string
+}
+
+// start begins a span for op and returns a function that ends it and records metrics.
+func (s instrumentedStorage) start(
+	ctx context.Context, op string, attr attribute.KeyValue,
+) (context.Context, func(error)) {
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, "storage."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("storage.backend", s.backend), attr),
+	)
+
+	return ctx, func(err error) {
+		storageDuration.WithLabelValues(s.backend, op).Observe(time.Since(start).Seconds())
+		if errors.Is(err, fs.ErrNotExist) {
+			span.End()
+			return
+		}
+		if err != nil {
+			storageErrors.WithLabelValues(s.backend, op).Inc()
+		}
+		endSpan(span, err)
+	}
+}
+
+func (s instrumentedStorage) Get(ctx context.Context, key string) (Object, error) {
+	ctx, done := s.start(ctx, "get", attribute.String("storage.key", key))
+	obj, err := s.Storage.Get(ctx, key)
+	done(err)
+	return obj, err
+}
+
+func (s instrumentedStorage) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	ctx, done := s.start(ctx, "stat", attribute.String("storage.key", key))
+	info, err := s.Storage.Stat(ctx, key)
+	done(err)
+	return info, err
+}
+
+func (s instrumentedStorage) Put(ctx context.Context, key string, r io.Reader, size int64, opts PutOptions) error {
+	ctx, done := s.start(ctx, "put", attribute.String("storage.key", key))
+	err := s.Storage.Put(ctx, key, r, size, opts)
+	done(err)
+	return err
+}
+
+func (s instrumentedStorage) List(ctx context.Context, prefix, startAfter string) iter.Seq2[ObjectInfo, error] {
+	return func(yield func(ObjectInfo, error) bool) {
+		ctx, done := s.start(ctx, "list", attribute.String("storage.prefix", prefix))
+		var err error
+		defer func() {
+			done(err)
+		}()
+
+		for info, listErr := range s.Storage.List(ctx, prefix, startAfter) {
+			err = listErr
+			if !yield(info, listErr) {
+				return
+			}
+		}
+	}
+}
+
+func (s instrumentedStorage) Delete(ctx context.Context, key string) error {
+	ctx, done := s.start(ctx, "delete", attribute.String("storage.key", key))
+	err := s.Storage.Delete(ctx, key)
+	done(err)
+	return err
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(metricsMiddleware)
+	r.Get("/opds/{year}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	counter := httpRequests.WithLabelValues(http.MethodGet, "/opds/{year}", "418")
+	before := testutil.ToFloat64(counter)
+
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/opds/2026", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.InDelta(t, before+1, testutil.ToFloat64(counter), 0)
+}
+
+func TestInstrumentedStorage(t *testing.T) {
+	store := instrumentedStorage{Storage: newMemStorage("2026/08/05.pdf"), backend: "test"}
+	errs := storageErrors.WithLabelValues("test", "stat")
+
+	_, err := store.Stat(t.Context(), "2026/08/05.pdf")
+	require.NoError(t, err)
+	_, err = store.Stat(t.Context(), "2026/08/06.pdf")
+	require.Error(t, err)
+	assert.Zero(t, testutil.ToFloat64(errs), "missing keys should not count as errors")
+
+	putErrs := storageErrors.WithLabelValues("test", "put")
+	before := testutil.ToFloat64(putErrs)
+	flaky := instrumentedStorage{Storage: &flakyStorage{memStorage: newMemStorage()}, backend: "test"}
+	require.Error(t, flaky.Put(t.Context(), "2026/08/05.pdf", strings.NewReader("%PDF-"), 5, PutOptions{}))
+	assert.InDelta(t, before+1, testutil.ToFloat64(putErrs), 0)
+
+	var keys []string
+	for info, err := range store.List(t.Context(), "", "") {
+		require.NoError(t, err)
+		keys = append(keys, info.Key)
+	}
+	assert.Equal(t, []string{"2026/08/05.pdf"}, keys)
+	assert.Zero(t, testutil.ToFloat64(storageErrors.WithLabelValues("test", "list")))
+}
+
+func TestUploadFailureReason(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("%w: %w", ErrInvalidURL, ErrForbiddenHost), "invalid_url"},
+		{ErrInvalidFilename, "invalid_filename"},
+		{fmt.Errorf("%w: 2026-08-05.pdf", ErrExists), "exists"},
+		{ErrNotPublished, "not_published"},
+		{ErrInvalidContent, "invalid_content"},
+		{fmt.Errorf("%w: 503 Service Unavailable", ErrUpstream), "upstream"},
+		{context.Canceled, "canceled"},
+		{errors.New("disk full"), "error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, uploadFailureReason(tt.err))
+		})
+	}
+}
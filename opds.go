@@ -0,0 +1,213 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	opdsNavigation  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	opdsAcquisition = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	opdsAcquire     = "http://opds-spec.org/acquisition"
+)
+
+// opdsGroup summarizes the issues stored in a year or month.
+type opdsGroup struct {
+	Count   int
+	Updated time.Time
+}
+
+// opdsRouter serves an OPDS 1.2 catalog.
+//
+// The root navigation feed links to recent issues and to each year, years link to their
+// months, and months are acquisition feeds with links to the issues served by get.
+func opdsRouter(conf *Config, store Storage, feed *Feed) http.Handler {
+	r := chi.NewRouter()
+
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		years := make(map[int]opdsGroup)
+		for issue, err := range listIssues(r.Context(), store, "20") {
+			if err != nil {
+				handleStorageError(w, err)
+				return
+			}
+			years[issue.Date.Year()] = years[issue.Date.Year()].add(issue)
+		}
+
+		base := baseURL(conf, r)
+		nav := newOPDSFeed(base, "/opds/", feedTitle, opdsNavigation)
+		nav.Entries = append(nav.Entries, opdsNavEntry(base, "/opds/recent", "Recent issues",
+			fmt.Sprintf("The %d most recent issues", conf.FeedSize), time.Time{}, opdsAcquisition))
+		for _, year := range slices.Backward(slices.Sorted(maps.Keys(years))) {
+			group := years[year]
+			nav.Entries = append(nav.Entries, opdsNavEntry(base, fmt.Sprintf("/opds/%04d", year), strconv.Itoa(year),
+				issueCount(group.Count), group.Updated, opdsNavigation))
+		}
+		writeXML(w, opdsNavigation+";charset=utf-8", nav)
+	})
+
+	r.Get("/recent", func(w http.ResponseWriter, r *http.Request) {
+		items, err := feed.Items(r.Context())
+		if err != nil {
+			handleStorageError(w, err)
+			return
+		}
+
+		base := baseURL(conf, r)
+		acq := newOPDSFeed(base, "/opds/recent", "Recent issues", opdsAcquisition)
+		acq.Links = append(acq.Links, atomLink{Rel: "up", Type: opdsNavigation, Href: base + "/opds/"})
+		for _, item := range items {
+			acq.Entries = append(acq.Entries, opdsIssueEntry(base, []StoredIssue{item}))
+		}
+		writeXML(w, opdsAcquisition+";charset=utf-8", acq)
+	})
+
+	r.Get("/{year}", func(w http.ResponseWriter, r *http.Request) {
+		year, err := time.Parse("2006", chi.URLParam(r, "year"))
+		if err != nil {
+			handleHTTPError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		months := make(map[time.Month]opdsGroup)
+		for issue, err := range listIssues(r.Context(), store, year.Format("2006/")) {
+			if err != nil {
+				handleStorageError(w, err)
+				return
+			}
+			months[issue.Date.Month()] = months[issue.Date.Month()].add(issue)
+		}
+		if len(months) == 0 {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+
+		base := baseURL(conf, r)
+		nav := newOPDSFeed(base, year.Format("/opds/2006"), year.Format("2006"), opdsNavigation)
+		nav.Links = append(nav.Links, atomLink{Rel: "up", Type: opdsNavigation, Href: base + "/opds/"})
+		for _, month := range slices.Backward(slices.Sorted(maps.Keys(months))) {
+			group := months[month]
+			date := time.Date(year.Year(), month, 1, 0, 0, 0, 0, time.UTC)
+			nav.Entries = append(nav.Entries, opdsNavEntry(base, date.Format("/opds/2006/01"), date.Format("January 2006"),
+				issueCount(group.Count), group.Updated, opdsAcquisition))
+		}
+		writeXML(w, opdsNavigation+";charset=utf-8", nav)
+	})
+
+	r.Get("/{year}/{month}", func(w http.ResponseWriter, r *http.Request) {
+		month, err := time.Parse("2006/01", chi.URLParam(r, "year")+"/"+chi.URLParam(r, "month"))
+		if err != nil {
+			handleHTTPError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Group every format of an issue into a single entry
+		var days [][]StoredIssue
+		for issue, err := range listIssues(r.Context(), store, month.Format("2006/01/")) {
+			if err != nil {
+				handleStorageError(w, err)
+				return
+			}
+			if n := len(days); n != 0 && days[n-1][0].Date.Equal(issue.Date) {
+				days[n-1] = append(days[n-1], issue)
+			} else {
+				days = append(days, []StoredIssue{issue})
+			}
+		}
+		if len(days) == 0 {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+
+		base := baseURL(conf, r)
+		acq := newOPDSFeed(base, month.Format("/opds/2006/01"), month.Format("January 2006"), opdsAcquisition)
+		acq.Links = append(acq.Links, atomLink{Rel: "up", Type: opdsNavigation, Href: base + month.Format("/opds/2006")})
+		for _, day := range slices.Backward(days) {
+			acq.Entries = append(acq.Entries, opdsIssueEntry(base, day))
+		}
+		writeXML(w, opdsAcquisition+";charset=utf-8", acq)
+	})
+
+	return r
+}
+
+func (g opdsGroup) add(issue StoredIssue) opdsGroup {
+	if issue.Ext == defaultExt {
+		g.Count++
+	}
+	if issue.Info.LastModified.After(g.Updated) {
+		g.Updated = issue.Info.LastModified
+	}
+	return g
+}
+
+func issueCount(n int) string {
+	if n == 1 {
+		return "1 issue"
+	}
+	return strconv.Itoa(n) + " issues"
+}
+
+func newOPDSFeed(base, path, title, kind string) atomFeed {
+	return atomFeed{
+		ID:      base + path,
+		Title:   title,
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Type: kind, Href: base + path},
+			{Rel: "start", Type: opdsNavigation, Href: base + "/opds/"},
+		},
+	}
+}
+
+func opdsNavEntry(base, path, title, content string, updated time.Time, kind string) atomEntry {
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	return atomEntry{
+		ID:      base + path,
+		Title:   title,
+		Updated: updated.UTC().Format(time.RFC3339),
+		Content: content,
+		Links:   []atomLink{{Rel: "subsection", Type: kind, Href: base + path}},
+	}
+}
+
+// opdsIssueEntry returns an entry with an acquisition link for every stored format of an issue.
+// The default format is listed first.
+func opdsIssueEntry(base string, formats []StoredIssue) atomEntry {
+	formats = slices.Clone(formats)
+	slices.SortStableFunc(formats, func(a, b StoredIssue) int {
+		return boolCmp(b.Ext == defaultExt, a.Ext == defaultExt)
+	})
+
+	issue := formats[0]
+	entry := atomEntry{
+		ID:        base + "/" + issue.ShortPath(),
+		Title:     issue.Date.Format(feedDateFormat),
+		Published: issue.Date.Format(time.RFC3339),
+		Links:     make([]atomLink, 0, len(formats)),
+	}
+
+	var updated time.Time
+	for _, format := range formats {
+		if format.Info.LastModified.After(updated) {
+			updated = format.Info.LastModified
+		}
+		entry.Links = append(entry.Links, atomLink{
+			Rel:    opdsAcquire,
+			Type:   contentType(format),
+			Length: format.Info.Size,
+			Href:   base + "/" + format.ShortPath(),
+		})
+	}
+	entry.Updated = cmp.Or(updated, issue.Date).UTC().Format(time.RFC3339)
+	return entry
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOPDSRouter(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+	conf := &Config{FeedSize: 10, PublicURL: "https://example.com"}
+	store := newMemStorage("2025/12/31.pdf", "2026/07/31.pdf", "2026/08/04.pdf", "2026/08/05.epub", "2026/08/05.pdf")
+	handler := http.StripPrefix("/opds", opdsRouter(conf, store, NewFeed(conf, store)))
+
+	get := func(t *testing.T, target string) (*httptest.ResponseRecorder, atomFeed) {
+		t.Helper()
+		r := httptest.NewRequestWithContext(t.Context(), http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		var feed atomFeed
+		if w.Code == http.StatusOK {
+			require.NoError(t, xml.Unmarshal(w.Body.Bytes(), &feed))
+		}
+		return w, feed
+	}
+
+	hrefs := func(feed atomFeed) []string {
+		hrefs := make([]string, 0, len(feed.Entries))
+		for _, entry := range feed.Entries {
+			for _, link := range entry.Links {
+				hrefs = append(hrefs, link.Href)
+			}
+		}
+		return hrefs
+	}
+
+	t.Run("root", func(t *testing.T) {
+		w, feed := get(t, "/opds/")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, opdsNavigation+";charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, []string{
+			"https://example.com/opds/recent",
+			"https://example.com/opds/2026",
+			"https://example.com/opds/2025",
+		}, hrefs(feed))
+		assert.Equal(t, "3 issues", feed.Entries[1].Content)
+	})
+
+	t.Run("recent", func(t *testing.T) {
+		w, feed := get(t, "/opds/recent")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, opdsAcquisition+";charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, []string{
+			"https://example.com/2026-08-05.pdf",
+			"https://example.com/2026-08-04.pdf",
+			"https://example.com/2026-07-31.pdf",
+			"https://example.com/2025-12-31.pdf",
+		}, hrefs(feed))
+	})
+
+	t.Run("year", func(t *testing.T) {
+		w, feed := get(t, "/opds/2026")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, []string{
+			"https://example.com/opds/2026/08",
+			"https://example.com/opds/2026/07",
+		}, hrefs(feed))
+		assert.Equal(t, "August 2026", feed.Entries[0].Title)
+		assert.Equal(t, "2 issues", feed.Entries[0].Content)
+	})
+
+	t.Run("month", func(t *testing.T) {
+		w, feed := get(t, "/opds/2026/08")
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Len(t, feed.Entries, 2)
+		assert.Equal(t, "Wednesday, August 5, 2026", feed.Entries[0].Title)
+		assert.Equal(t, []string{
+			"https://example.com/2026-08-05.pdf",
+			"https://example.com/2026-08-05.epub",
+			"https://example.com/2026-08-04.pdf",
+		}, hrefs(feed))
+		for _, link := range feed.Entries[0].Links {
+			assert.Equal(t, opdsAcquire, link.Rel)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		w, _ := get(t, "/opds/2024")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		w, _ = get(t, "/opds/2024/01")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		w, _ := get(t, "/opds/2026/13")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	checkOK   = "ok"
+	checkFail = "fail"
+
+	storageCheckTimeout = 5 * time.Second
+)
+
+var ErrLatestUnknown = errors.New("latest issue is not known yet")
+
+// ReadyCheck is the result of a single readiness check.
+type ReadyCheck struct {
+	Status  string    `json:"status"`
+	Error   string    `json:"error,omitempty"`
+	Checked time.Time `json:"checked,omitzero"`
+}
+
+func newReadyCheck(err error, checked time.Time) ReadyCheck {
+	check := ReadyCheck{Status: checkOK, Checked: checked}
+	if err != nil {
+		check.Status = checkFail
+		check.Error = err.Error()
+	}
+	return check
+}
+
+// NewReadiness returns a Readiness that checks store and, if it isn't nil, scheduler.
+func NewReadiness(conf *Config, store Storage, scheduler *Scheduler) *Readiness {
+	return &Readiness{conf: conf, store: store, scheduler: scheduler}
+}
+
+// Readiness reports whether the server can serve issues.
+type Readiness struct {
+	conf      *Config
+	store     Storage
+	scheduler *Scheduler
+
+	mu         sync.Mutex
+	checked    time.Time
+	storageErr error
+}
+
+// checkStorage lists a single object to verify the storage backend is reachable.
+// Results are cached for conf.ReadyCacheTTL so that frequent probes don't hit the backend.
+func (rd *Readiness) checkStorage(ctx context.Context) (time.Time, error) {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+
+	if !rd.checked.IsZero() && time.Since(rd.checked) < rd.conf.ReadyCacheTTL {
+		return rd.checked, rd.storageErr
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, storageCheckTimeout)
+	defer cancel()
+
+	// A Stat of a missing key can't tell a missing bucket apart from a missing object, so list instead.
+	var err error
+	for _, err = range rd.store.List(ctx, "", "") {
+		break
+	}
+
+	rd.checked, rd.storageErr = time.Now(), err
+	return rd.checked, err
+}
+
+// checkFetch fails if scheduled fetches have been failing for longer than conf.ReadyMaxFetchFailure.
+func (rd *Readiness) checkFetch() error {
+	since := rd.scheduler.FailingSince()
+	if !since.IsZero() && time.Since(since) > rd.conf.ReadyMaxFetchFailure {
+		last := rd.scheduler.Last()
+		return fmt.Errorf("failing since %s: %s", since.Format(time.RFC3339), last.Error)
+	}
+	return nil
+}
+
+// handler responds with a JSON breakdown of every check, with status 503 if any failed.
+func (rd *Readiness) handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		checks := make(map[string]ReadyCheck, 3)
+
+		checked, err := rd.checkStorage(r.Context())
+		checks["storage"] = newReadyCheck(err, checked)
+
+		// An empty bucket is ready; only a failed attempt to find the latest issue is not.
+		if err = latest.Err(); err != nil && latest.Load() == nil {
+			err = fmt.Errorf("%w: %w", ErrLatestUnknown, err)
+		}
+		checks["latest"] = newReadyCheck(err, time.Time{})
+
+		if rd.scheduler != nil {
+			checks["fetch"] = newReadyCheck(rd.checkFetch(), time.Time{})
+		}
+
+		status, code := checkOK, http.StatusOK
+		for _, check := range checks {
+			if check.Status != checkOK {
+				status, code = checkFail, http.StatusServiceUnavailable
+				break
+			}
+		}
+
+		writeJSON(w, code, struct {
+			Status string                `json:"status"`
+			Checks map[string]ReadyCheck `json:"checks"`
+		}{status, checks})
+	}
+}
+
+// serveAt returns a middleware that serves GET and HEAD requests for path with h,
+// like middleware.Heartbeat, and passes every other request on.
+func serveAt(path string, h http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if (r.Method == http.MethodGet || r.Method == http.MethodHead) && r.URL.Path == path {
+				h.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
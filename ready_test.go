@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// brokenStorage fails every List while err is set.
+type brokenStorage struct {
+	*memStorage
+	err   atomic.Pointer[error]
+	lists atomic.Int32
+}
+
+func (b *brokenStorage) List(ctx context.Context, prefix, startAfter string) iter.Seq2[ObjectInfo, error] {
+	b.lists.Add(1)
+	if err := b.err.Load(); err != nil {
+		return func(yield func(ObjectInfo, error) bool) {
+			yield(ObjectInfo{}, *err)
+		}
+	}
+	return b.memStorage.List(ctx, prefix, startAfter)
+}
+
+type readyResponse struct {
+	Status string                `json:"status"`
+	Checks map[string]ReadyCheck `json:"checks"`
+}
+
+func getReady(t *testing.T, rd *Readiness) (int, readyResponse) {
+	t.Helper()
+	r := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/readyz", nil)
+	w := httptest.NewRecorder()
+	rd.handler()(w, r)
+
+	var res readyResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	return w.Code, res
+}
+
+func TestReadiness(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+
+	store := &brokenStorage{memStorage: newMemStorage("2026/08/05.pdf")}
+	conf := &Config{ReadyCacheTTL: time.Hour}
+	rd := NewReadiness(conf, store, nil)
+
+	_, err := refreshLatest(t.Context(), store)
+	require.NoError(t, err)
+	code, res := getReady(t, rd)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, checkOK, res.Status)
+	assert.Equal(t, checkOK, res.Checks["storage"].Status)
+	assert.Equal(t, checkOK, res.Checks["latest"].Status)
+	assert.NotContains(t, res.Checks, "fetch", "fetch should only be checked when scheduled")
+
+	// Storage results are cached
+	storeErr := errors.New("connection refused")
+	store.err.Store(&storeErr)
+	code, _ = getReady(t, rd)
+	assert.Equal(t, http.StatusOK, code)
+	assert.EqualValues(t, 2, store.lists.Load(), "one list by refreshLatest and one by the storage check")
+
+	conf.ReadyCacheTTL = 0
+	code, res = getReady(t, rd)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "connection refused", res.Checks["storage"].Error)
+
+	// Failed discovery is reported by the latest check
+	latest.Store(nil)
+	_, refreshErr := refreshLatest(t.Context(), store)
+	require.Error(t, refreshErr)
+	_, res = getReady(t, rd)
+	assert.Equal(t, ErrLatestUnknown.Error()+": connection refused", res.Checks["latest"].Error)
+}
+
+func TestReadiness_emptyBucket(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+
+	store := newMemStorage()
+	_, err := refreshLatest(t.Context(), store)
+	require.NoError(t, err)
+	require.Nil(t, latest.Load())
+
+	code, res := getReady(t, NewReadiness(&Config{}, store, nil))
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, checkOK, res.Checks["latest"].Status)
+}
+
+func TestReadiness_fetch(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+	latest.Store(NewIssueFromDate(time.Date(2026, 8, 5, 0, 0, 0, 0, time.UTC), ".pdf"))
+
+	conf := &Config{
+		FetchSchedule:        "0 6 * * *",
+		FetchURL:             "https://example.com",
+		ReadyMaxFetchFailure: time.Hour,
+	}
+	s, err := NewScheduler(conf, nil, &Calendar{})
+	require.NoError(t, err)
+	rd := NewReadiness(conf, newMemStorage(), s)
+
+	code, res := getReady(t, rd)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, checkOK, res.Checks["fetch"].Status)
+
+	s.setLast(FetchRun{Started: time.Now().Add(-30 * time.Minute), Error: "upstream error"})
+	code, _ = getReady(t, rd)
+	assert.Equal(t, http.StatusOK, code, "recent failures should be tolerated")
+
+	s.setLast(FetchRun{Started: time.Now().Add(-2 * time.Hour), Error: "upstream error"})
+	code, _ = getReady(t, rd)
+	assert.Equal(t, http.StatusOK, code, "failure start should be kept across runs")
+
+	s.failingSince = time.Now().Add(-2 * time.Hour)
+	code, res = getReady(t, rd)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, res.Checks["fetch"].Error, "upstream error")
+
+	s.setLast(FetchRun{Started: time.Now(), Issue: "2026-08-06.pdf"})
+	code, _ = getReady(t, rd)
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestServeAt(t *testing.T) {
+	limited := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	probe := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := serveAt("/readyz", probe)(limited)
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/readyz", http.StatusOK},
+		{http.MethodHead, "/readyz", http.StatusOK},
+		{http.MethodPost, "/readyz", http.StatusTooManyRequests},
+		{http.MethodGet, "/readyz/", http.StatusTooManyRequests},
+		{http.MethodGet, "/", http.StatusTooManyRequests},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			r := httptest.NewRequestWithContext(t.Context(), tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+)
+
+// latestName is the alias for the latest issue, like `/latest` or `/latest.pdf`.
+const latestName = "latest"
+
+// relativeDate returns the issue date that a relative name refers to.
+// Names are `today`, `yesterday` or a lowercase weekday like `monday`, which refers
+// to the most recent such day, including today. ok is false for any other name.
+func relativeDate(name string, today time.Time) (time.Time, bool) {
+	switch name {
+	case "today":
+		return today, true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), true
+	}
+
+	for d := range 7 {
+		if weekday := time.Weekday(d); name == strings.ToLower(weekday.String()) {
+			days := (int(today.Weekday()) - d + 7) % 7
+			return today.AddDate(0, 0, -days), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// resolveIssue resolves a request path to an issue.
+//
+// Aliases like `latest.pdf`, `today`, `monday` or a date without an extension resolve
+// to a stored issue, with alias set so that the caller can redirect to its canonical path.
+// If the alias has no extension, the format is chosen by conf.LatestFormats.
+// Dates with an extension resolve without checking storage, and other paths resolve to nil.
+//
+// If no issue is stored for an alias, the error wraps fs.ErrNotExist. Unless the alias is
+// `latest`, the requested issue is still returned, with an empty Ext if none was given.
+func resolveIssue(ctx context.Context, conf *Config, store Storage, p string) (*Issue, bool, error) {
+	ext := path.Ext(p)
+	name := strings.TrimSuffix(p, ext)
+
+	if name == latestName {
+		var issue *Issue
+		if ext == "" {
+			issue = latest.Preferred(conf.LatestFormats)
+		} else {
+			issue = latest.Get(ext)
+		}
+		if issue == nil {
+			return nil, true, fmt.Errorf("%w: no latest issue", fs.ErrNotExist)
+		}
+		return issue, true, nil
+	}
+
+	date, ok := relativeDate(name, issueToday())
+	if !ok {
+		issue, err := NewIssueFromPath(p)
+		if err != nil {
+			return nil, false, nil
+		}
+		if ext != "" {
+			return issue, false, nil
+		}
+		date = issue.Date
+	}
+
+	if ext != "" {
+		issue := NewIssueFromDate(date, ext)
+		if _, err := store.Stat(ctx, issue.FullPath()); err != nil {
+			return issue, true, err
+		}
+		return issue, true, nil
+	}
+
+	issue, err := issueOn(ctx, store, date, conf.LatestFormats)
+	if err != nil {
+		return NewIssueFromDate(date, ""), true, err
+	}
+	return issue, true, nil
+}
+
+// issueOn returns the issue stored for date, choosing between formats like latestIssues.Preferred.
+// If none is stored, the error wraps fs.ErrNotExist.
+func issueOn(ctx context.Context, store Storage, date time.Time, formats []string) (*Issue, error) {
+	if len(formats) == 0 {
+		formats = []string{defaultExt}
+	}
+
+	var best *Issue
+	for issue, err := range listIssues(ctx, store, date.Format("2006/01/02.")) {
+		if err != nil {
+			return nil, err
+		}
+		if best == nil || preferIssue(issue.Issue, best, formats) {
+			best = issue.Issue
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("%w: no issue for %s", fs.ErrNotExist, date.Format(time.DateOnly))
+	}
+	return best, nil
+}
+
+// Fallback selects which issue to serve when the requested one isn't stored.
+type Fallback string
+
+const (
+	FallbackNone     Fallback = "none"
+	FallbackPrevious Fallback = "previous"
+	FallbackNext     Fallback = "next"
+
+	// fallbackMaxMonths limits how many monthly listings nearestIssue reads.
+	fallbackMaxMonths = 12
+)
+
+var ErrInvalidFallback = errors.New("invalid fallback")
+
+func (f *Fallback) UnmarshalText(text []byte) error {
+	switch v := Fallback(text); v {
+	case FallbackNone, FallbackPrevious, FallbackNext:
+		*f = v
+		return nil
+	default:
+		return fmt.Errorf("%w: %q must be one of none, previous or next", ErrInvalidFallback, text)
+	}
+}
+
+// nearestIssue returns the closest stored issue before or after date, as selected by dir.
+// If ext is empty, any format matches and formats choose between formats like issueOn.
+//
+// Stored issues are found by listing a month at a time, up to fallbackMaxMonths.
+// If none is found, the error wraps fs.ErrNotExist.
+func nearestIssue(
+	ctx context.Context, store Storage, date time.Time, ext string, dir Fallback, formats []string,
+) (*Issue, error) {
+	if len(formats) == 0 {
+		formats = []string{defaultExt}
+	}
+
+	step := 1
+	if dir == FallbackPrevious {
+		step = -1
+	}
+
+	month := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location())
+	for range fallbackMaxMonths {
+		var best *Issue
+		for stored, err := range listIssues(ctx, store, month.Format("2006/01/")) {
+			if err != nil {
+				return nil, err
+			}
+
+			issue := stored.Issue
+			switch {
+			case ext != "" && issue.Ext != ext,
+				dir == FallbackPrevious && !issue.Date.Before(date),
+				dir == FallbackNext && !issue.Date.After(date):
+				continue
+			case best == nil,
+				dir == FallbackPrevious && issue.Date.After(best.Date),
+				dir == FallbackNext && issue.Date.Before(best.Date),
+				issue.Date.Equal(best.Date) && preferIssue(issue, best, formats):
+				best = issue
+			}
+		}
+		if best != nil {
+			return best, nil
+		}
+		month = month.AddDate(0, step, 0)
+	}
+	return nil, fmt.Errorf("%w: no %s issue for %s", fs.ErrNotExist, dir, date.Format(time.DateOnly))
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelativeDate(t *testing.T) {
+	// A Wednesday
+	today := time.Date(2026, 8, 5, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{"today", "2026-08-05", true},
+		{"yesterday", "2026-08-04", true},
+		{"wednesday", "2026-08-05", true},
+		{"tuesday", "2026-08-04", true},
+		{"monday", "2026-08-03", true},
+		{"sunday", "2026-08-02", true},
+		{"thursday", "2026-07-30", true},
+		{"Monday", "", false},
+		{"latest", "", false},
+		{"2026-08-05", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := relativeDate(tt.name, today)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.Equal(t, tt.want, got.Format(time.DateOnly))
+			}
+		})
+	}
+}
+
+func TestFallback_UnmarshalText(t *testing.T) {
+	var f Fallback
+	require.NoError(t, f.UnmarshalText([]byte("previous")))
+	assert.Equal(t, FallbackPrevious, f)
+	require.ErrorIs(t, f.UnmarshalText([]byte("closest")), ErrInvalidFallback)
+	assert.Equal(t, FallbackPrevious, f, "should be unchanged on error")
+}
+
+func TestNearestIssue(t *testing.T) {
+	store := newMemStorage(
+		"2026/06/30.pdf",
+		"2026/08/07.epub",
+		"2026/08/08.pdf",
+		"2026/08/10.epub",
+		"2026/08/10.pdf",
+		"2026/09/01.pdf",
+	)
+	sunday := time.Date(2026, 8, 9, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		date    time.Time
+		ext     string
+		dir     Fallback
+		formats []string
+		want    string
+	}{
+		{"previous", sunday, ".pdf", FallbackPrevious, nil, "2026-08-08.pdf"},
+		{"next", sunday, ".pdf", FallbackNext, nil, "2026-08-10.pdf"},
+		{"matches ext", sunday, ".epub", FallbackPrevious, nil, "2026-08-07.epub"},
+		{"any format", sunday, "", FallbackNext, []string{".epub", ".pdf"}, "2026-08-10.epub"},
+		{"default format", sunday, "", FallbackNext, nil, "2026-08-10.pdf"},
+		{"excludes date", time.Date(2026, 8, 10, 0, 0, 0, 0, time.UTC), ".pdf", FallbackNext, nil, "2026-09-01.pdf"},
+		{"previous month", time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC), ".pdf", FallbackPrevious, nil, "2026-06-30.pdf"},
+		{"none", time.Date(2026, 9, 2, 0, 0, 0, 0, time.UTC), ".pdf", FallbackNext, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nearestIssue(t.Context(), store, tt.date, tt.ext, tt.dir, tt.formats)
+			if tt.want == "" {
+				require.ErrorIs(t, err, fs.ErrNotExist)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.ShortPath())
+		})
+	}
+}
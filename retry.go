@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy configures how failed operations are retried.
+type RetryPolicy struct {
+	// Maximum number of attempts, including the first.
+	MaxAttempts int `env:"MAX_ATTEMPTS,notEmpty" envDefault:"3"`
+	// Delay before the first retry. Doubles after every attempt.
+	InitialBackoff time.Duration `env:"INITIAL_BACKOFF,notEmpty" envDefault:"1s"`
+	// Upper bound for the delay between attempts.
+	MaxBackoff time.Duration `env:"MAX_BACKOFF,notEmpty" envDefault:"30s"`
+	// Random fraction of the delay to add or subtract, from 0 to 1.
+	Jitter float64 `env:"JITTER" envDefault:"0.2"`
+}
+
+// permanentError marks an error that should not be retried.
+type permanentError struct{ err error }
+
+func (p permanentError) Error() string { return p.err.Error() }
+func (p permanentError) Unwrap() error { return p.err }
+
+// Permanent wraps err so that RetryPolicy.Do returns it without retrying.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// Do calls fn until it succeeds, returns a Permanent error, ctx is canceled,
+// or p.MaxAttempts is reached. Failed attempts are logged with op.
+func (p RetryPolicy) Do(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	attempts := max(p.MaxAttempts, 1)
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			if attempt > 1 {
+				slog.Info("Retry succeeded", "op", op, "attempt", attempt)
+			}
+			return nil
+		}
+
+		var permanent permanentError
+		switch {
+		case errors.As(err, &permanent):
+			return permanent.err
+		case ctx.Err() != nil:
+			return err
+		case attempt >= attempts:
+			slog.Error("Giving up", "op", op, "attempt", attempt, "error", err)
+			return err
+		}
+
+		delay := p.backoff(attempt)
+		slog.Warn("Attempt failed", "op", op, "attempt", attempt, "retry", delay, "error", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the delay after the given failed attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.InitialBackoff
+	for range attempt - 1 {
+		delay *= 2
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 {
+		delay = min(delay, p.MaxBackoff)
+	}
+
+	if jitter := min(max(p.Jitter, 0), 1); jitter > 0 {
+		delay += time.Duration((rand.Float64()*2 - 1) * jitter * float64(delay)) //nolint:gosec // Jitter doesn't need crypto
+	}
+	return delay
+}
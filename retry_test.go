@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errFlaky = errors.New("flaky")
+
+func TestRetryPolicy_Do(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+
+	t.Run("succeeds after retry", func(t *testing.T) {
+		var calls int
+		err := p.Do(t.Context(), "test", func(context.Context) error {
+			calls++
+			if calls < 3 {
+				return errFlaky
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		var calls int
+		err := p.Do(t.Context(), "test", func(context.Context) error {
+			calls++
+			return errFlaky
+		})
+		require.ErrorIs(t, err, errFlaky)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("permanent", func(t *testing.T) {
+		var calls int
+		err := p.Do(t.Context(), "test", func(context.Context) error {
+			calls++
+			return Permanent(errFlaky)
+		})
+		require.ErrorIs(t, err, errFlaky)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, p.backoff(1))
+	assert.Equal(t, 2*time.Second, p.backoff(2))
+	assert.Equal(t, 4*time.Second, p.backoff(3))
+	assert.Equal(t, 5*time.Second, p.backoff(4))
+	assert.Equal(t, 5*time.Second, p.backoff(100))
+
+	p.Jitter = 0.5
+	for range 100 {
+		d := p.backoff(2)
+		assert.GreaterOrEqual(t, d, time.Second)
+		assert.LessOrEqual(t, d, 3*time.Second)
+	}
+}
+
+// flakyStorage fails the first Put.
+type flakyStorage struct {
+	*memStorage
+	calls atomic.Int32
+}
+
+func (f *flakyStorage) Put(ctx context.Context, key string, r io.Reader, size int64, opts PutOptions) error {
+	if f.calls.Add(1) == 1 {
+		_, _ = io.CopyN(io.Discard, r, 4)
+		return errFlaky
+	}
+	return f.memStorage.Put(ctx, key, r, size, opts)
+}
+
+func TestFetcher_Fetch_retry(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+
+	files := newUpstream(t)
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		http.Redirect(w, r, files+"/files/a1b2-issue-8-5-2026.pdf", http.StatusFound)
+	}))
+	t.Cleanup(upstream.Close)
+
+	policy := RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}
+	conf := newTestConfig()
+	conf.UpstreamRetry = policy
+	conf.UpstreamRetryStatusCodes = []int{http.StatusServiceUnavailable}
+	conf.StorageRetry = policy
+	store := &flakyStorage{memStorage: newMemStorage()}
+	fetcher := NewFetcher(conf, store)
+
+	u, err := url.Parse(upstream.URL + paperPath)
+	require.NoError(t, err)
+
+	issue, err := fetcher.Fetch(t.Context(), u, FetchOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "2026-08-05.pdf", issue.ShortPath())
+	assert.EqualValues(t, 2, hits.Load())
+	assert.EqualValues(t, 2, store.calls.Load())
+
+	obj, err := store.Get(t.Context(), issueKey)
+	require.NoError(t, err)
+	b, err := io.ReadAll(obj)
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-1.4 fake", string(b), "retried write should start from the beginning")
+
+	t.Run("non-retryable status", func(t *testing.T) {
+		u, err := url.Parse(files + "/gone")
+		require.NoError(t, err)
+		_, err = fetcher.Fetch(t.Context(), u, FetchOptions{})
+		require.ErrorIs(t, err, ErrUpstream)
+	})
+}
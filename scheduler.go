@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+var ErrMissingFetchURL = errors.New("FETCH_URL is required when FETCH_SCHEDULE is set")
+
+// NewScheduler returns a Scheduler that fetches conf.FetchURL on conf.FetchSchedule,
+// skipping days that calendar has no issue for.
+func NewScheduler(conf *Config, fetcher *Fetcher, calendar *Calendar) (*Scheduler, error) {
+	schedule, err := cron.ParseStandard(conf.FetchSchedule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fetch schedule: %w", err)
+	}
+
+	if conf.FetchURL == "" {
+		return nil, ErrMissingFetchURL
+	}
+	u, err := parseSourceURL(conf.FetchURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Scheduler{conf: conf, fetcher: fetcher, calendar: calendar, schedule: schedule, url: u}, nil
+}
+
+// Scheduler periodically fetches the daily issue.
+//
+// Each run retries until the issue for the current date in conf.PublicationTimezone is stored,
+// or until conf.FetchRetryTimeout has passed. Runs on days without a paper are skipped.
+type Scheduler struct {
+	conf     *Config
+	fetcher  *Fetcher
+	calendar *Calendar
+	schedule cron.Schedule
+	url      *url.URL
+
+	mu   sync.Mutex
+	next time.Time
+	last *FetchRun
+	// failingSince is when the first failed run since the last success started.
+	failingSince time.Time
+}
+
+// FetchRun is the result of a scheduled fetch.
+type FetchRun struct {
+	Expected string    `json:"expected"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished,omitzero"`
+	Attempts int       `json:"attempts"`
+	Issue    string    `json:"issue,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	// Skipped is set if no issue is published on the expected date.
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// Run blocks, fetching the issue on schedule until ctx is canceled.
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		next := s.schedule.Next(time.Now().In(s.conf.FetchTimezone))
+		s.mu.Lock()
+		s.next = next
+		s.mu.Unlock()
+		slog.Info("Scheduled fetch", "at", next)
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.RunOnce(ctx)
+	}
+}
+
+// RunOnce fetches the issue for today, retrying until it has been stored.
+func (s *Scheduler) RunOnce(ctx context.Context) {
+	expect := issueToday()
+	run := &FetchRun{Expected: expect.Format(time.DateOnly), Started: time.Now()}
+	log := slog.With("expected", run.Expected)
+
+	if !s.calendar.Publishes(expect) {
+		log.Info("No issue is published today, skipping scheduled fetch")
+		run.Skipped = true
+		run.Finished = run.Started
+		s.setLast(*run)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.conf.FetchRetryTimeout)
+	defer cancel()
+
+	for {
+		run.Attempts++
+		issue, err := s.fetcher.Fetch(ctx, s.url, FetchOptions{Expect: expect})
+		if err == nil || errors.Is(err, ErrExists) {
+			run.Issue = issue.ShortPath()
+			run.Error = ""
+			log.Info("Scheduled fetch succeeded", "issue", issue, "attempts", run.Attempts)
+			break
+		}
+
+		if ctx.Err() != nil {
+			// The attempt was interrupted by the timeout. Keep the previous upstream error,
+			// which is more useful than the context error.
+			if run.Error == "" {
+				run.Error = err.Error()
+			}
+			log.Error("Scheduled fetch gave up", "error", run.Error, "attempts", run.Attempts)
+			run.Finished = time.Now()
+			s.setLast(*run)
+			return
+		}
+
+		run.Error = err.Error()
+		log.Warn("Scheduled fetch failed", "error", err, "attempt", run.Attempts, "retry", s.conf.FetchRetryInterval)
+		s.setLast(*run)
+
+		timer := time.NewTimer(s.conf.FetchRetryInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			log.Error("Scheduled fetch gave up", "error", err, "attempts", run.Attempts)
+			run.Finished = time.Now()
+			s.setLast(*run)
+			return
+		case <-timer.C:
+		}
+	}
+
+	run.Finished = time.Now()
+	s.setLast(*run)
+}
+
+func (s *Scheduler) setLast(run FetchRun) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last = &run
+	switch {
+	case run.Skipped:
+		// Failures continue until an issue is fetched.
+	case run.Error == "":
+		s.failingSince = time.Time{}
+	case s.failingSince.IsZero():
+		s.failingSince = run.Started
+	}
+}
+
+// FailingSince returns when scheduled fetches started failing, or the zero time
+// if the last run succeeded.
+func (s *Scheduler) FailingSince() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failingSince
+}
+
+// Last returns the most recent run, or nil if none has started.
+func (s *Scheduler) Last() *FetchRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last == nil {
+		return nil
+	}
+	last := *s.last
+	return &last
+}
+
+// statusHandler reports the schedule and the result of the last run as JSON.
+func (s *Scheduler) statusHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		s.mu.Lock()
+		status := struct {
+			Schedule string    `json:"schedule"`
+			Timezone string    `json:"timezone"`
+			Next     time.Time `json:"next,omitzero"`
+			Last     *FetchRun `json:"last"`
+		}{
+			Schedule: s.conf.FetchSchedule,
+			Timezone: s.conf.FetchTimezone.String(),
+			Next:     s.next,
+			Last:     s.last,
+		}
+		s.mu.Unlock()
+
+		writeJSON(w, http.StatusOK, status)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler_RunOnce(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+
+	today := time.Now().UTC()
+	yesterday := today.AddDate(0, 0, -1)
+
+	// The upstream serves yesterday's issue until the second attempt.
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == paperPath {
+			d := yesterday
+			if hits.Add(1) > 1 {
+				d = today
+			}
+			http.Redirect(w, r, "/files/a1b2-issue-"+d.Format("1-2-2006")+".pdf", http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("%PDF-1.4 fake"))
+	}))
+	t.Cleanup(upstream.Close)
+
+	store := newMemStorage()
+	conf := newTestConfig()
+	conf.FetchSchedule = "0 6 * * *"
+	conf.FetchTimezone = time.UTC
+	conf.FetchURL = upstream.URL + paperPath
+	conf.FetchRetryInterval = time.Millisecond
+	conf.FetchRetryTimeout = time.Minute
+	s, err := NewScheduler(conf, NewFetcher(conf, store), &Calendar{})
+	require.NoError(t, err)
+
+	s.RunOnce(t.Context())
+
+	last := s.Last()
+	require.NotNil(t, last)
+	assert.Equal(t, 2, last.Attempts)
+	assert.Equal(t, today.Format(time.DateOnly)+".pdf", last.Issue)
+	assert.Empty(t, last.Error)
+	assert.Equal(t, []string{today.Format("2006/01/02.pdf")}, store.Keys(), "should not store the stale issue")
+}
+
+func TestScheduler_RunOnce_timeout(t *testing.T) {
+	// The first attempt fails, and the second hangs until the retry timeout cancels it.
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) > 1 {
+			<-r.Context().Done()
+			return
+		}
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(upstream.Close)
+
+	conf := newTestConfig()
+	conf.FetchSchedule = "0 6 * * *"
+	conf.FetchTimezone = time.UTC
+	conf.FetchURL = upstream.URL
+	conf.FetchRetryInterval = time.Millisecond
+	conf.FetchRetryTimeout = 100 * time.Millisecond
+	s, err := NewScheduler(conf, NewFetcher(conf, newMemStorage()), &Calendar{})
+	require.NoError(t, err)
+
+	s.RunOnce(t.Context())
+
+	last := s.Last()
+	require.NotNil(t, last)
+	assert.Equal(t, 2, last.Attempts)
+	assert.Contains(t, last.Error, "503", "should keep the upstream error rather than the timeout")
+	assert.False(t, last.Finished.IsZero())
+}
+
+func TestScheduler_RunOnce_skipped(t *testing.T) {
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(upstream.Close)
+
+	conf := newTestConfig()
+	conf.FetchSchedule = "0 6 * * *"
+	conf.FetchURL = upstream.URL
+	conf.PublicationDays = []string{issueToday().AddDate(0, 0, 1).Weekday().String()}
+	calendar, err := NewCalendar(conf)
+	require.NoError(t, err)
+	s, err := NewScheduler(conf, NewFetcher(conf, newMemStorage()), calendar)
+	require.NoError(t, err)
+
+	failed := time.Now().Add(-time.Hour)
+	s.setLast(FetchRun{Started: failed, Error: "upstream error"})
+	s.RunOnce(t.Context())
+
+	last := s.Last()
+	require.NotNil(t, last)
+	assert.True(t, last.Skipped)
+	assert.Zero(t, last.Attempts)
+	assert.Zero(t, hits.Load(), "should not fetch on days without a paper")
+	assert.Equal(t, failed, s.FailingSince(), "skipped runs should not clear failures")
+}
+
+func TestNewScheduler(t *testing.T) {
+	_, err := NewScheduler(&Config{FetchSchedule: "nope", FetchURL: "https://example.com"}, nil, &Calendar{})
+	require.Error(t, err)
+
+	_, err = NewScheduler(&Config{FetchSchedule: "0 6 * * *"}, nil, &Calendar{})
+	require.ErrorIs(t, err, ErrMissingFetchURL)
+}
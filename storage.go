@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"iter"
+	"net/http"
+	"time"
+)
+
+// Storage is a key-value store for issue files.
+//
+// Keys are slash-separated paths such as those returned by Issue.FullPath.
+// Implementations must return an error wrapping fs.ErrNotExist when a key is missing.
+type Storage interface {
+	// Get opens the object at key for reading.
+	Get(ctx context.Context, key string) (Object, error)
+	// Stat returns the object's metadata without reading its contents.
+	Stat(ctx context.Context, key string) (ObjectInfo, error)
+	// Put stores the contents of r at key. A negative size means the size is unknown.
+	Put(ctx context.Context, key string, r io.Reader, size int64, opts PutOptions) error
+	// List iterates over every object whose key starts with prefix, in lexical order.
+	// If startAfter isn't empty, only keys that sort after it are included.
+	List(ctx context.Context, prefix, startAfter string) iter.Seq2[ObjectInfo, error]
+	// Delete removes the object at key.
+	Delete(ctx context.Context, key string) error
+}
+
+// Object is an open object returned by Storage.Get.
+type Object interface {
+	io.ReadSeekCloser
+	Info() ObjectInfo
+}
+
+// ObjectInfo describes a stored object.
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ETag         string
+	LastModified time.Time
+	ContentType  string
+}
+
+// PutOptions holds optional metadata for Storage.Put.
+type PutOptions struct {
+	ContentType        string
+	ContentDisposition string
+}
+
+const (
+	StorageS3 = "s3"
+	StorageFS = "fs"
+)
+
+var ErrUnknownStorage = errors.New("unknown storage backend")
+
+// NewStorage returns the storage backend configured in conf, instrumented with metrics
+// and maintaining the issue index.
+func NewStorage(conf *Config) (Storage, error) { //nolint:ireturn // Backend is chosen at runtime
+	switch conf.Storage {
+	case StorageS3:
+		s3, err := NewS3(conf)
+		if err != nil {
+			return nil, err
+		}
+		return &indexedStorage{Storage: instrumentedStorage{Storage: s3, backend: StorageS3}}, nil
+	case StorageFS:
+		local, err := NewFSStorage(conf.FSPath)
+		if err != nil {
+			return nil, err
+		}
+		return &indexedStorage{Storage: instrumentedStorage{Storage: local, backend: StorageFS}}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownStorage, conf.Storage)
+	}
+}
+
+func handleStorageError(w http.ResponseWriter, err error) {
+	if errors.Is(err, fs.ErrNotExist) {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	} else {
+		handleHTTPError(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"iter"
+	"mime"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NewFSStorage returns a Storage rooted at dir, creating it if needed.
+func NewFSStorage(dir string) (*FSStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FSStorage{root: root, etags: make(map[string]fsETag)}, nil
+}
+
+// FSStorage stores issues as files in a local directory, using keys as relative paths.
+//
+// Writes go to a temporary file which is renamed into place, so readers never see partial files.
+// ETags are a SHA-256 of the file contents, computed on write or on first access and cached
+// until the file's size or mtime changes. List only reports ETags that are already cached.
+// Content types are derived from the file extension.
+type FSStorage struct {
+	root *os.Root
+
+	mu    sync.Mutex
+	etags map[string]fsETag
+}
+
+type fsETag struct {
+	size    int64
+	modTime time.Time
+	etag    string
+}
+
+func (s *FSStorage) Get(_ context.Context, key string) (Object, error) {
+	f, err := s.root.Open(key)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := f.Stat()
+	if err == nil && stat.IsDir() {
+		err = &fs.PathError{Op: "get", Path: key, Err: fs.ErrNotExist}
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	info := s.info(key, stat)
+	if info.ETag == "" {
+		if info.ETag, err = s.hash(key, stat, f); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+
+	return &fsObject{File: f, info: info}, nil
+}
+
+func (s *FSStorage) Stat(_ context.Context, key string) (ObjectInfo, error) {
+	stat, err := s.root.Stat(key)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	if stat.IsDir() {
+		return ObjectInfo{}, &fs.PathError{Op: "stat", Path: key, Err: fs.ErrNotExist}
+	}
+
+	info := s.info(key, stat)
+	if info.ETag == "" {
+		f, err := s.root.Open(key)
+		if err != nil {
+			return ObjectInfo{}, err
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+
+		if info.ETag, err = s.hash(key, stat, f); err != nil {
+			return ObjectInfo{}, err
+		}
+	}
+	return info, nil
+}
+
+func (s *FSStorage) Put(_ context.Context, key string, r io.Reader, _ int64, _ PutOptions) error {
+	dir, name := path.Split(key)
+	if dir != "" {
+		if err := s.root.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+
+	tmp := dir + "." + name + "." + rand.Text() + ".tmp"
+	f, err := s.root.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_ = f.Close()
+			_ = s.root.Remove(tmp)
+		}
+	}()
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, h), r); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := s.root.Rename(tmp, key); err != nil {
+		return err
+	}
+	committed = true
+
+	if stat, err := s.root.Stat(key); err == nil {
+		s.storeETag(key, stat, hex.EncodeToString(h.Sum(nil)))
+	}
+	return nil
+}
+
+// List walks the directories under prefix and yields files as they are found, so a caller
+// that stops early doesn't read the whole tree. Directories that only hold keys up to
+// startAfter are not read.
+func (s *FSStorage) List(_ context.Context, prefix, startAfter string) iter.Seq2[ObjectInfo, error] {
+	return func(yield func(ObjectInfo, error) bool) {
+		start := path.Dir(prefix)
+		if strings.HasSuffix(prefix, "/") {
+			start = strings.TrimSuffix(prefix, "/")
+		}
+		s.walk(s.root.FS(), start, prefix, startAfter, yield)
+	}
+}
+
+// walk yields the files in dir that match prefix and sort after startAfter in lexical key order,
+// returning false once iteration should stop.
+func (s *FSStorage) walk(fsys fs.FS, dir, prefix, startAfter string, yield func(ObjectInfo, error) bool) bool {
+	entries, err := fs.ReadDir(fsys, dir)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return true
+	case err != nil:
+		yield(ObjectInfo{}, err)
+		return false
+	}
+
+	// Entries are sorted by name, but the keys in a directory sort as if its name had a trailing slash.
+	sortKey := func(d fs.DirEntry) string {
+		if d.IsDir() {
+			return d.Name() + "/"
+		}
+		return d.Name()
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int { return strings.Compare(sortKey(a), sortKey(b)) })
+
+	for _, d := range entries {
+		p := path.Join(dir, d.Name())
+		switch {
+		case strings.HasPrefix(d.Name(), "."):
+			// Skip hidden entries like in-progress writes.
+			continue
+		case d.IsDir():
+			if !strings.HasPrefix(p+"/", prefix) && !strings.HasPrefix(prefix, p+"/") {
+				continue
+			}
+			if startAfter > p+"/" && !strings.HasPrefix(startAfter, p+"/") {
+				// Every key in the directory sorts before startAfter.
+				continue
+			}
+			if !s.walk(fsys, p, prefix, startAfter, yield) {
+				return false
+			}
+		case !strings.HasPrefix(p, prefix) || p <= startAfter || !d.Type().IsRegular():
+			continue
+		default:
+			stat, err := d.Info()
+			if err != nil {
+				yield(ObjectInfo{}, err)
+				return false
+			}
+			if !yield(s.info(p, stat), nil) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (s *FSStorage) Delete(_ context.Context, key string) error {
+	if err := s.root.Remove(key); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.etags, key)
+	s.mu.Unlock()
+	return nil
+}
+
+// info builds an ObjectInfo for key, filling in the ETag if it is cached.
+func (s *FSStorage) info(key string, stat fs.FileInfo) ObjectInfo {
+	info := ObjectInfo{
+		Key:          key,
+		Size:         stat.Size(),
+		LastModified: stat.ModTime(),
+		ContentType:  mime.TypeByExtension(path.Ext(key)),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.etags[key]; ok && v.size == stat.Size() && v.modTime.Equal(stat.ModTime()) {
+		info.ETag = v.etag
+	}
+	return info
+}
+
+// hash computes the ETag for the contents of r and caches it.
+func (s *FSStorage) hash(key string, stat fs.FileInfo, r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	etag := hex.EncodeToString(h.Sum(nil))
+	s.storeETag(key, stat, etag)
+	return etag, nil
+}
+
+func (s *FSStorage) storeETag(key string, stat fs.FileInfo, etag string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.etags[key] = fsETag{size: stat.Size(), modTime: stat.ModTime(), etag: etag}
+}
+
+type fsObject struct {
+	*os.File
+	info ObjectInfo
+}
+
+func (o *fsObject) Info() ObjectInfo { return o.info }
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFSStorage(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFSStorage(dir)
+	require.NoError(t, err)
+
+	const body = "%PDF-1.4 fake"
+	require.NoError(t, store.Put(t.Context(), "2026/08/05.pdf", strings.NewReader(body), -1, PutOptions{}))
+	require.NoError(t, store.Put(t.Context(), "2026/08/04.pdf", strings.NewReader(body), -1, PutOptions{}))
+	require.NoError(t, store.Put(t.Context(), "2025/12/31.pdf", strings.NewReader(body), -1, PutOptions{}))
+
+	t.Run("layout", func(t *testing.T) {
+		b, err := os.ReadFile(filepath.Join(dir, "2026", "08", "05.pdf"))
+		require.NoError(t, err)
+		assert.Equal(t, body, string(b))
+
+		entries, err := os.ReadDir(filepath.Join(dir, "2026", "08"))
+		require.NoError(t, err)
+		assert.Len(t, entries, 2, "temp files should be renamed into place")
+	})
+
+	t.Run("get", func(t *testing.T) {
+		obj, err := store.Get(t.Context(), "2026/08/05.pdf")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = obj.Close() })
+
+		b, err := io.ReadAll(obj)
+		require.NoError(t, err)
+		assert.Equal(t, body, string(b))
+
+		info := obj.Info()
+		assert.Equal(t, "2026/08/05.pdf", info.Key)
+		assert.EqualValues(t, len(body), info.Size)
+		assert.Equal(t, "application/pdf", info.ContentType)
+		assert.NotEmpty(t, info.ETag)
+		assert.False(t, info.LastModified.IsZero())
+	})
+
+	t.Run("etag survives restart", func(t *testing.T) {
+		want, err := store.Stat(t.Context(), "2026/08/05.pdf")
+		require.NoError(t, err)
+
+		reopened, err := NewFSStorage(dir)
+		require.NoError(t, err)
+		got, err := reopened.Stat(t.Context(), "2026/08/05.pdf")
+		require.NoError(t, err)
+		assert.Equal(t, want.ETag, got.ETag)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		var keys []string
+		for info, err := range store.List(t.Context(), "2026/", "") {
+			require.NoError(t, err)
+			keys = append(keys, info.Key)
+		}
+		assert.Equal(t, []string{"2026/08/04.pdf", "2026/08/05.pdf"}, keys)
+
+		keys = nil
+		for info, err := range store.List(t.Context(), "20", "") {
+			require.NoError(t, err)
+			keys = append(keys, info.Key)
+		}
+		assert.Equal(t, []string{"2025/12/31.pdf", "2026/08/04.pdf", "2026/08/05.pdf"}, keys)
+
+		keys = nil
+		for info, err := range store.List(t.Context(), "20", "2026/08/04.pdf") {
+			require.NoError(t, err)
+			keys = append(keys, info.Key)
+		}
+		assert.Equal(t, []string{"2026/08/05.pdf"}, keys, "should start after the key")
+
+		for _, err := range store.List(t.Context(), "2024/", "") {
+			require.NoError(t, err)
+			assert.Fail(t, "expected no results")
+		}
+	})
+
+	t.Run("list order", func(t *testing.T) {
+		// "-" sorts before "/", so 2026-notes.txt comes before every key in 2026/.
+		require.NoError(t, store.Put(t.Context(), "2026-notes.txt", strings.NewReader("notes"), -1, PutOptions{}))
+		t.Cleanup(func() { _ = store.Delete(t.Context(), "2026-notes.txt") })
+
+		var keys []string
+		for info, err := range store.List(t.Context(), "", "") {
+			require.NoError(t, err)
+			keys = append(keys, info.Key)
+		}
+		assert.Equal(t, []string{"2025/12/31.pdf", "2026-notes.txt", "2026/08/04.pdf", "2026/08/05.pdf"}, keys)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := store.Get(t.Context(), "2026/08/06.pdf")
+		require.ErrorIs(t, err, fs.ErrNotExist)
+		_, err = store.Stat(t.Context(), "2026/08")
+		require.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	t.Run("escape", func(t *testing.T) {
+		_, err := store.Get(t.Context(), "../outside.pdf")
+		require.Error(t, err)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		require.NoError(t, store.Delete(t.Context(), "2026/08/04.pdf"))
+		_, err := store.Stat(t.Context(), "2026/08/04.pdf")
+		require.ErrorIs(t, err, fs.ErrNotExist)
+	})
+}
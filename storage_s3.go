@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"iter"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+var ErrMissingS3Config = errors.New("S3_ENDPOINT and S3_BUCKET are required for S3 storage")
+
+func NewS3(conf *Config) (*S3Storage, error) {
+	if conf.S3Endpoint == "" || conf.S3Bucket == "" {
+		return nil, ErrMissingS3Config
+	}
+
+	u, err := url.Parse(conf.S3Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &minio.Options{
+		Creds: credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.IAM{},
+		}),
+		Secure: u.Scheme == "https",
+		Region: conf.S3Region,
+	}
+
+	client, err := minio.New(u.Host, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewS3Storage(client, conf.S3Bucket), nil
+}
+
+// NewS3Storage returns a Storage backed by bucket.
+func NewS3Storage(client *minio.Client, bucket string) *S3Storage {
+	return &S3Storage{client: client, bucket: bucket}
+}
+
+// S3Storage stores issues in an S3-compatible bucket.
+type S3Storage struct {
+	client *minio.Client
+	bucket string
+}
+
+func (s *S3Storage) Get(ctx context.Context, key string) (Object, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, s3Error("get", key, err)
+	}
+
+	// GetObject is lazy, so errors like a missing key only surface once the object is used.
+	stat, err := obj.Stat()
+	if err != nil {
+		_ = obj.Close()
+		return nil, s3Error("get", key, err)
+	}
+
+	return &s3Object{Object: obj, info: s3Info(stat)}, nil
+}
+
+func (s *S3Storage) Stat(ctx context.Context, key string) (ObjectInfo, error) {
+	stat, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return ObjectInfo{}, s3Error("stat", key, err)
+	}
+	return s3Info(stat), nil
+}
+
+func (s *S3Storage) Put(ctx context.Context, key string, r io.Reader, size int64, opts PutOptions) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{
+		ContentType:        opts.ContentType,
+		ContentDisposition: opts.ContentDisposition,
+	})
+	if err != nil {
+		return s3Error("put", key, err)
+	}
+	return nil
+}
+
+func (s *S3Storage) List(ctx context.Context, prefix, startAfter string) iter.Seq2[ObjectInfo, error] {
+	return func(yield func(ObjectInfo, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		for item := range s.client.ListObjectsIter(ctx, s.bucket, minio.ListObjectsOptions{
+			Prefix:     prefix,
+			StartAfter: startAfter,
+			Recursive:  true,
+		}) {
+			if item.Err != nil {
+				yield(ObjectInfo{}, s3Error("list", prefix, item.Err))
+				return
+			}
+			if !yield(s3Info(item), nil) {
+				return
+			}
+		}
+	}
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	if err := s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{}); err != nil {
+		return s3Error("delete", key, err)
+	}
+	return nil
+}
+
+type s3Object struct {
+	*minio.Object
+	info ObjectInfo
+}
+
+func (o *s3Object) Info() ObjectInfo { return o.info }
+
+func s3Info(info minio.ObjectInfo) ObjectInfo {
+	return ObjectInfo{
+		Key:          info.Key,
+		Size:         info.Size,
+		ETag:         strings.Trim(info.ETag, `"`),
+		LastModified: info.LastModified,
+		ContentType:  info.ContentType,
+	}
+}
+
+// s3Error maps a MinIO "not found" response to fs.ErrNotExist.
+func s3Error(op, key string, err error) error {
+	if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+		err = fs.ErrNotExist
+	}
+	return &fs.PathError{Op: op, Path: key, Err: err}
+}
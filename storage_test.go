@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5" //nolint:gosec // Only used to mimic S3 ETags
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"iter"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memStorage is an in-memory Storage for tests.
+type memStorage struct {
+	mu      sync.Mutex
+	objects map[string]memObject
+}
+
+type memObject struct {
+	data []byte
+	info ObjectInfo
+}
+
+func newMemStorage(keys ...string) *memStorage {
+	m := &memStorage{objects: make(map[string]memObject)}
+	for _, key := range keys {
+		m.add(key, "%PDF-1.4 "+key)
+	}
+	return m
+}
+
+func (m *memStorage) add(key, data string) {
+	_ = m.Put(context.Background(), key, strings.NewReader(data), int64(len(data)), PutOptions{})
+}
+
+func (m *memStorage) Keys() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Sorted(maps.Keys(m.objects))
+}
+
+func (m *memStorage) Get(_ context.Context, key string) (Object, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	obj, ok := m.objects[key]
+	if !ok {
+		return nil, &fs.PathError{Op: "get", Path: key, Err: fs.ErrNotExist}
+	}
+	return &memReader{Reader: bytes.NewReader(obj.data), info: obj.info}, nil
+}
+
+func (m *memStorage) Stat(_ context.Context, key string) (ObjectInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	obj, ok := m.objects[key]
+	if !ok {
+		return ObjectInfo{}, &fs.PathError{Op: "stat", Path: key, Err: fs.ErrNotExist}
+	}
+	return obj.info, nil
+}
+
+func (m *memStorage) Put(_ context.Context, key string, r io.Reader, _ int64, opts PutOptions) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	sum := md5.Sum(data) //nolint:gosec // Only used to mimic S3 ETags
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = memObject{
+		data: data,
+		info: ObjectInfo{
+			Key:          key,
+			Size:         int64(len(data)),
+			ETag:         hex.EncodeToString(sum[:]),
+			LastModified: time.Now().Truncate(time.Second),
+			ContentType:  opts.ContentType,
+		},
+	}
+	return nil
+}
+
+func (m *memStorage) List(_ context.Context, prefix, startAfter string) iter.Seq2[ObjectInfo, error] {
+	return func(yield func(ObjectInfo, error) bool) {
+		m.mu.Lock()
+		infos := make([]ObjectInfo, 0, len(m.objects))
+		for key, obj := range m.objects {
+			if strings.HasPrefix(key, prefix) && key > startAfter {
+				infos = append(infos, obj.info)
+			}
+		}
+		m.mu.Unlock()
+
+		slices.SortFunc(infos, func(a, b ObjectInfo) int { return strings.Compare(a.Key, b.Key) })
+		for _, info := range infos {
+			if !yield(info, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (m *memStorage) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.objects[key]; !ok {
+		return &fs.PathError{Op: "delete", Path: key, Err: fs.ErrNotExist}
+	}
+	delete(m.objects, key)
+	return nil
+}
+
+type memReader struct {
+	*bytes.Reader
+	info ObjectInfo
+}
+
+func (r *memReader) Close() error     { return nil }
+func (r *memReader) Info() ObjectInfo { return r.info }
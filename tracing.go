@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/httptrace/otelhttptrace"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TracesExporterNone = "none"
+	TracesExporterOTLP = "otlp"
+
+	serviceName = "wsj-dl"
+)
+
+var ErrUnknownTracesExporter = errors.New("unknown traces exporter")
+
+//nolint:gochecknoglobals
+var tracer = otel.Tracer("gabe565.com/wsj-dl")
+
+// setupTracing installs the global tracer provider configured in conf.
+// The returned function flushes pending spans and must be called before exiting.
+//
+// With the `none` exporter the default no-op provider is kept, so spans cost next to nothing.
+func setupTracing(ctx context.Context, conf *Config) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	switch conf.TracesExporter {
+	case TracesExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case TracesExporterOTLP:
+		var err error
+		// Endpoint, headers and TLS are read from the standard OTEL_EXPORTER_OTLP_* variables.
+		if exporter, err = otlptracehttp.New(ctx); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTracesExporter, conf.TracesExporter)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithTelemetrySDK(),
+		// Allows OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES to override the defaults.
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+	return provider.Shutdown, nil
+}
+
+// tracingMiddleware starts a server span for every request, continuing any trace
+// propagated by the client. Spans are named after the chi route pattern once it is known.
+func tracingMiddleware(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+
+		if route := routePattern(r); route != "" {
+			span := trace.SpanFromContext(r.Context())
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+	}), "http.server")
+}
+
+// tracingTransport wraps an upstream transport with a client span for every request,
+// including each redirect hop, and child spans for DNS, connect and TLS.
+func tracingTransport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base,
+		otelhttp.WithClientTrace(func(ctx context.Context) *httptrace.ClientTrace {
+			return otelhttptrace.NewClientTrace(ctx)
+		}),
+		// Don't leak trace headers to third-party hosts.
+		otelhttp.WithPropagators(propagation.NewCompositeTextMapPropagator()),
+	)
+}
+
+// endSpan records err on span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
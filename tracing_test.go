@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+//nolint:gochecknoglobals
+var (
+	spanExporter     = tracetest.NewInMemoryExporter()
+	setupSpanCapture = sync.OnceFunc(func() {
+		// The global provider can only be delegated to once, so every test shares it.
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spanExporter)))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+)
+
+// captureSpans records the spans ended during the test.
+func captureSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	setupSpanCapture()
+	spanExporter.Reset()
+	t.Cleanup(spanExporter.Reset)
+	return spanExporter
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	spans := captureSpans(t)
+
+	r := chi.NewRouter()
+	r.Use(tracingMiddleware)
+	r.Get("/opds/{year}", func(http.ResponseWriter, *http.Request) {})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/opds/2026", nil)
+	req.Header.Set("Traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	got := spans.GetSpans()
+	require.Len(t, got, 1)
+	assert.Equal(t, "GET /opds/{year}", got[0].Name)
+	assert.Equal(t, traceID, got[0].SpanContext.TraceID().String())
+	assert.Contains(t, got[0].Attributes, attribute.String("http.route", "/opds/{year}"))
+}
+
+func TestInstrumentedStorage_spans(t *testing.T) {
+	spans := captureSpans(t)
+
+	store := instrumentedStorage{Storage: &flakyStorage{memStorage: newMemStorage()}, backend: "test"}
+	_, err := store.Stat(t.Context(), "2026/08/05.pdf")
+	require.Error(t, err)
+	require.Error(t, store.Put(t.Context(), "2026/08/05.pdf", strings.NewReader("%PDF-"), 5, PutOptions{}))
+
+	got := spans.GetSpans()
+	require.Len(t, got, 2)
+	assert.Equal(t, "storage.stat", got[0].Name)
+	assert.Equal(t, codes.Unset, got[0].Status.Code, "missing keys should not be errors")
+	assert.Contains(t, got[0].Attributes, attribute.String("storage.key", "2026/08/05.pdf"))
+	assert.Equal(t, "storage.put", got[1].Name)
+	assert.Equal(t, codes.Error, got[1].Status.Code)
+}
+
+func TestFetcher_Fetch_spans(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+	spans := captureSpans(t)
+
+	files := newUpstream(t)
+	fetcher := NewFetcher(newTestConfig(), instrumentedStorage{Storage: newMemStorage(), backend: "test"})
+	u, err := url.Parse(files + paperPath)
+	require.NoError(t, err)
+	_, err = fetcher.Fetch(t.Context(), u, FetchOptions{})
+	require.NoError(t, err)
+
+	names := make(map[string]int)
+	var root sdktrace.ReadOnlySpan
+	for _, span := range spans.GetSpans().Snapshots() {
+		names[span.Name()]++
+		if span.Name() == "Fetcher.Fetch" {
+			root = span
+		}
+	}
+	require.NotNil(t, root)
+	assert.Equal(t, 2, names["HTTP GET"], "every redirect hop should have a client span")
+	assert.Equal(t, 1, names["Fetcher.Save"])
+	assert.Equal(t, 1, names["storage.put"])
+	for _, span := range spans.GetSpans() {
+		assert.Equal(t, root.SpanContext().TraceID(), span.SpanContext.TraceID())
+	}
+}
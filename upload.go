@@ -1,112 +1,532 @@
 package main
 
 import (
+	"context"
 	"crypto/subtle"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"net/url"
+	"os"
 	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
-	"github.com/minio/minio-go/v7"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // defaultExt is used when the download URL has no file extension.
 const defaultExt = ".pdf"
 
-// uploadHandler downloads the PDF at the `url` param and stores it in S3.
+var (
+	ErrNotPublished = errors.New("issue not published yet")
+	ErrExists       = errors.New("issue already exists")
+)
+
+// uploadHandler downloads the PDF at the `url` param and stores it.
 //
 // The issue date is parsed from the filename of the final URL after redirects. An optional
 // `date` param in YYYY-MM-DD format overrides it for URLs that don't follow that format.
-func uploadHandler(conf *Config, s3 *minio.Client) http.HandlerFunc {
+//
+// Instead of a URL, a file may be sent as the `file` part of a multipart form. Its date is
+// parsed from the `date` param or the uploaded filename. File uploads are always synchronous.
+//
+// Existing issues are not replaced unless the `force` param is true.
+//
+// If the `async` param is true (or conf.UploadAsync is set and `async` is omitted), the upload
+// is queued on jobs and the handler responds with 202 Accepted and the job's status.
+func uploadHandler(conf *Config, fetcher *Fetcher, jobs *JobQueue) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if subtle.ConstantTimeCompare(
-			[]byte(r.Header.Get("Authorization")), []byte(conf.UploadAuthKey),
-		) != 1 {
+		if !authorized(conf, r) {
 			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 			return
 		}
 
+		if conf.UploadMaxSize > 0 {
+			// Leave room for the other form fields and multipart headers.
+			r.Body = http.MaxBytesReader(w, r.Body, conf.UploadMaxSize+1<<20)
+		}
+
+		opts, async, err := parseUploadForm(conf, r)
+		if err != nil {
+			handleHTTPError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		file, header, err := r.FormFile("file")
+		switch {
+		case err == nil:
+			defer func() {
+				_ = file.Close()
+			}()
+			uploadFile(w, r, fetcher, file, header, opts)
+			return
+		case !errors.Is(err, http.ErrMissingFile) && !errors.Is(err, http.ErrNotMultipart):
+			handleHTTPError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		src := r.FormValue("url")
 		if src == "" {
 			handleHTTPError(w, "Missing url", http.StatusBadRequest)
 			return
 		}
 
-		u, err := url.Parse(src)
+		u, err := parseSourceURL(src)
 		if err != nil {
 			handleHTTPError(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		if u.Scheme != "http" && u.Scheme != "https" {
-			handleHTTPError(w, "URL scheme must be http or https", http.StatusBadRequest)
-			return
-		}
 
-		var date time.Time
-		if v := r.FormValue("date"); v != "" {
-			if date, err = time.Parse(time.DateOnly, v); err != nil {
-				handleHTTPError(w, err.Error(), http.StatusBadRequest)
+		if async {
+			job, err := jobs.Enqueue(r.Context(), u, opts)
+			if err != nil {
+				handleHTTPError(w, err.Error(), http.StatusServiceUnavailable)
 				return
 			}
-		}
 
-		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, u.String(), nil)
-		if err != nil {
-			handleHTTPError(w, err.Error(), http.StatusBadRequest)
+			w.Header().Set("Location", "/api/jobs/"+job.ID)
+			writeJSON(w, http.StatusAccepted, job.Status())
 			return
 		}
-		req.Header.Set("User-Agent", conf.UploadUserAgent)
 
-		res, err := http.DefaultClient.Do(req)
+		issue, err := fetcher.Fetch(r.Context(), u, opts)
 		if err != nil {
-			handleHTTPError(w, err.Error(), http.StatusBadGateway)
+			handleFetchError(w, err)
 			return
 		}
-		defer func() {
-			_, _ = io.Copy(io.Discard, res.Body)
-			_ = res.Body.Close()
-		}()
 
-		if res.StatusCode != http.StatusOK {
-			handleHTTPError(w, fmt.Errorf("%w: %s", ErrUpstream, res.Status).Error(), http.StatusBadGateway)
-			return
+		writeUploadResult(w, issue)
+	}
+}
+
+// parseUploadForm parses the `date`, `force` and `async` params shared by all upload types.
+func parseUploadForm(conf *Config, r *http.Request) (FetchOptions, bool, error) {
+	var opts FetchOptions
+	var err error
+	if v := r.FormValue("date"); v != "" {
+		if opts.Date, err = parseIssueDate(time.DateOnly, v); err != nil {
+			return opts, false, err
+		}
+	}
+
+	if v := r.FormValue("force"); v != "" {
+		if opts.Force, err = strconv.ParseBool(v); err != nil {
+			return opts, false, err
 		}
+	}
 
-		// Request is the last request in the redirect chain, so its URL holds the real filename.
-		u = res.Request.URL
+	async := conf.UploadAsync
+	if v := r.FormValue("async"); v != "" {
+		if async, err = strconv.ParseBool(v); err != nil {
+			return opts, false, err
+		}
+	}
+	return opts, async, nil
+}
+
+// uploadFile stores a file sent in a multipart form.
+func uploadFile(
+	w http.ResponseWriter, r *http.Request, fetcher *Fetcher,
+	file multipart.File, header *multipart.FileHeader, opts FetchOptions,
+) {
+	ext := path.Ext(header.Filename)
+	if ext == "" {
+		ext = defaultExt
+	}
 
-		var issue *Issue
-		if date.IsZero() {
-			if issue, err = NewIssueFromUpstream(u.Path); err != nil {
+	var issue *Issue
+	if !opts.Date.IsZero() {
+		issue = NewIssueFromDate(opts.Date, ext)
+	} else {
+		var err error
+		if issue, err = NewIssueFromPath(header.Filename); err != nil {
+			if issue, err = NewIssueFromUpstream(header.Filename); err != nil {
 				handleHTTPError(w, err.Error(), http.StatusBadRequest)
 				return
 			}
-		} else {
-			ext := path.Ext(u.Path)
-			if ext == "" {
-				ext = defaultExt
-			}
-			issue = NewIssueFromDate(date, ext)
 		}
+		if issue.Ext == "" {
+			issue.Ext = defaultExt
+		}
+	}
+
+	err := fetcher.Save(r.Context(), issue, file, header.Size, header.Header.Get("Content-Type"), opts)
+	observeUpload(uploadSourceFile, err)
+	if err != nil {
+		handleFetchError(w, err)
+		return
+	}
+
+	slog.Info("Uploaded file", "filename", issue, "name", header.Filename)
+	writeUploadResult(w, issue)
+}
+
+func writeUploadResult(w http.ResponseWriter, issue *Issue) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = io.WriteString(w, "/"+issue.ShortPath()+"\n")
+}
+
+// authorized reports whether r has the upload auth key.
+func authorized(conf *Config, r *http.Request) bool {
+	return subtle.ConstantTimeCompare(
+		[]byte(r.Header.Get("Authorization")), []byte(conf.UploadAuthKey),
+	) == 1
+}
+
+var ErrInvalidURL = errors.New("invalid url")
+
+// parseSourceURL parses an upstream download URL, only allowing http and https.
+func parseSourceURL(src string) (*url.URL, error) {
+	u, err := url.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%w: URL scheme must be http or https", ErrInvalidURL)
+	}
+	return u, nil
+}
+
+// FetchOptions configures Fetcher.Fetch.
+type FetchOptions struct {
+	// Date overrides the issue date parsed from the download URL.
+	// If Expect is also set, Date is only used when the filename has no date.
+	Date time.Time
+	// Expect, if set, aborts the fetch with ErrNotPublished before anything is stored
+	// when the upstream issue has a different date.
+	Expect time.Time
+	// Force replaces an existing issue. Otherwise, the fetch fails with ErrExists.
+	Force bool
+	// OnStore, if set, is called with the download size once the body has been
+	// downloaded and is about to be written to storage.
+	OnStore func(size int64)
+	// Transferred, if set, is incremented as the body is read.
+	Transferred *atomic.Int64
+}
+
+// NewFetcher returns a Fetcher that stores issues in store.
+func NewFetcher(conf *Config, store Storage) *Fetcher {
+	return &Fetcher{conf: conf, store: store, client: NewUploadClient(conf)}
+}
+
+// Fetcher downloads issues from upstream and stores them.
+type Fetcher struct {
+	conf   *Config
+	store  Storage
+	client *http.Client
+	onSave []func(*Issue)
+}
+
+// OnSave registers fn to be called with every issue that Save stores.
+// It must be called before the Fetcher is used.
+func (f *Fetcher) OnSave(fn func(*Issue)) {
+	f.onSave = append(f.onSave, fn)
+}
+
+// Fetch downloads the issue at u and stores it with Save.
+//
+// The issue date is parsed from the filename of the final URL after redirects, unless
+// opts.Date is set.
+func (f *Fetcher) Fetch(ctx context.Context, u *url.URL, opts FetchOptions) (*Issue, error) {
+	ctx, span := tracer.Start(ctx, "Fetcher.Fetch", trace.WithAttributes(attribute.String("url.full", u.String())))
+	issue, err := f.fetch(ctx, u, opts)
+	observeUpload(uploadSourceURL, err)
+	endSpan(span, err)
+	return issue, err
+}
+
+func (f *Fetcher) fetch(ctx context.Context, u *url.URL, opts FetchOptions) (*Issue, error) {
+	if err := checkUploadURL(f.conf, u); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidURL, err)
+	}
 
-		_, err = s3.PutObject(r.Context(), conf.S3Bucket, issue.FullPath(), res.Body, res.ContentLength,
-			minio.PutObjectOptions{
-				ContentType:        res.Header.Get("Content-Type"),
-				ContentDisposition: "attachment; filename=" + issue.ShortPath(),
-			},
-		)
+	if !opts.Date.IsZero() && !opts.Force {
+		// The key is already known, so skip the download if the issue is stored.
+		issue := newIssueFromURLDate(opts.Date, u)
+		switch _, err := f.store.Stat(ctx, issue.FullPath()); {
+		case err == nil:
+			return issue, fmt.Errorf("%w: %s", ErrExists, issue)
+		case !errors.Is(err, fs.ErrNotExist):
+			return nil, err
+		}
+	}
+
+	res, err := f.download(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(res.Body)
+
+	// Request is the last request in the redirect chain, so its URL holds the real filename.
+	u = res.Request.URL
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("upstream.url", u.String()))
+
+	var issue *Issue
+	switch {
+	case opts.Date.IsZero():
+		if issue, err = NewIssueFromUpstream(u.Path); err != nil {
+			return nil, err
+		}
+	case !opts.Expect.IsZero():
+		// Prefer the filename's date so that a redirect to another issue is caught below.
+		if issue, err = NewIssueFromUpstream(u.Path); err != nil {
+			issue = newIssueFromURLDate(opts.Date, u)
+		}
+	default:
+		issue = newIssueFromURLDate(opts.Date, u)
+	}
+
+	if !opts.Expect.IsZero() && !issue.Date.Equal(opts.Expect) {
+		return nil, fmt.Errorf("%w: upstream has %s", ErrNotPublished, issue)
+	}
+
+	// Wrap read errors so that a broken connection is reported as an upstream error.
+	body := upstreamReader{r: res.Body}
+	if err := f.Save(ctx, issue, body, res.ContentLength, res.Header.Get("Content-Type"), opts); err != nil {
+		if errors.Is(err, ErrExists) {
+			return issue, err
+		}
+		return nil, err
+	}
+
+	slog.Info("Loaded file", "filename", issue, "url", u.String())
+	return issue, nil
+}
+
+// Save validates the contents of r and stores them as issue.
+//
+// size is only used to reject oversized uploads early and may be -1 if unknown.
+// The body is spooled to a temporary file so that storage writes can be retried.
+func (f *Fetcher) Save(
+	ctx context.Context, issue *Issue, r io.Reader, size int64, contentType string, opts FetchOptions,
+) error {
+	ctx, span := tracer.Start(ctx, "Fetcher.Save", trace.WithAttributes(
+		attribute.String("issue", issue.String()),
+		attribute.Bool("force", opts.Force),
+	))
+	err := f.save(ctx, issue, r, size, contentType, opts)
+	endSpan(span, err)
+	return err
+}
+
+func (f *Fetcher) save(
+	ctx context.Context, issue *Issue, r io.Reader, size int64, contentType string, opts FetchOptions,
+) error {
+	key := issue.FullPath()
+	existing, err := f.store.Stat(ctx, key)
+	switch {
+	case err == nil:
+		if !opts.Force {
+			return fmt.Errorf("%w: %s", ErrExists, issue)
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return err
+	}
+
+	if err := checkContentType(f.conf, contentType); err != nil {
+		return err
+	}
+	if size > 0 {
+		if err := checkSize(f.conf, size); err != nil {
+			return err
+		}
+	}
+
+	if f.conf.UploadMaxSize > 0 {
+		// Read one byte past the limit so that oversized bodies fail validation.
+		r = io.LimitReader(r, f.conf.UploadMaxSize+1)
+	}
+	if opts.Transferred != nil {
+		r = &countingReader{r: r, n: opts.Transferred}
+	}
+
+	spool, size, err := spoolBody(r)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = spool.Close()
+		_ = os.Remove(spool.Name())
+	}()
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("size", size))
+	if err := validateContent(f.conf, issue, spool, size); err != nil {
+		return err
+	}
+
+	if opts.OnStore != nil {
+		opts.OnStore(size)
+	}
+
+	if existing.Key != "" && f.conf.UploadKeepBackups {
+		if err := f.backup(ctx, existing); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", issue, err)
+		}
+	}
+
+	err = f.conf.StorageRetry.Do(ctx, "store "+key, func(ctx context.Context) error {
+		if _, err := spool.Seek(0, io.SeekStart); err != nil {
+			return Permanent(err)
+		}
+		return f.store.Put(ctx, key, spool, size, PutOptions{
+			ContentType:        contentType,
+			ContentDisposition: "attachment; filename=" + issue.ShortPath(),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	storeLatest(issue)
+	for _, fn := range f.onSave {
+		fn(issue)
+	}
+	return nil
+}
+
+// backupPrefix is where replaced issues are kept. It must not start with the
+// year prefix that findLatest lists.
+const backupPrefix = "backups/"
+
+// backup copies an issue that is about to be replaced to a key under backupPrefix
+// that includes its last modified time.
+func (f *Fetcher) backup(ctx context.Context, info ObjectInfo) error {
+	obj, err := f.store.Get(ctx, info.Key)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = obj.Close()
+	}()
+
+	ext := path.Ext(info.Key)
+	key := backupPrefix + strings.TrimSuffix(info.Key, ext) + "." +
+		info.LastModified.UTC().Format("20060102T150405Z") + ext
+	if err := f.store.Put(ctx, key, obj, info.Size, PutOptions{ContentType: info.ContentType}); err != nil {
+		return err
+	}
+
+	slog.Info("Backed up replaced file", "key", info.Key, "backup", key)
+	return nil
+}
+
+// download requests u, retrying network errors and retryable status codes
+// according to conf.UpstreamRetry. The caller must close the response body.
+func (f *Fetcher) download(ctx context.Context, u *url.URL) (*http.Response, error) {
+	var res *http.Response
+	err := f.conf.UpstreamRetry.Do(ctx, "download "+u.String(), func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 		if err != nil {
-			handleHTTPError(w, err.Error(), http.StatusInternalServerError)
-			return
+			return Permanent(fmt.Errorf("%w: %w", ErrInvalidURL, err))
 		}
+		req.Header.Set("User-Agent", f.conf.UploadUserAgent)
+
+		r, err := f.client.Do(req)
+		switch {
+		case errors.Is(err, ErrForbiddenHost), errors.Is(err, ErrForbiddenAddress),
+			errors.Is(err, ErrForbiddenScheme), errors.Is(err, ErrTooManyRedirects):
+			return Permanent(fmt.Errorf("%w: %w", ErrInvalidURL, err))
+		case err != nil:
+			return fmt.Errorf("%w: %w", ErrUpstream, err)
+		}
+		upstreamResponses.WithLabelValues(strconv.Itoa(r.StatusCode)).Inc()
+
+		if r.StatusCode != http.StatusOK {
+			closeBody(r.Body)
+
+			err := fmt.Errorf("%w: %s", ErrUpstream, r.Status)
+			if !slices.Contains(f.conf.UpstreamRetryStatusCodes, r.StatusCode) {
+				return Permanent(err)
+			}
+			return err
+		}
+
+		res = r
+		return nil
+	})
+	return res, err
+}
+
+// newIssueFromURLDate returns the issue for date, with the extension of u or defaultExt.
+func newIssueFromURLDate(date time.Time, u *url.URL) *Issue {
+	ext := path.Ext(u.Path)
+	if ext == "" {
+		ext = defaultExt
+	}
+	return NewIssueFromDate(date, ext)
+}
+
+// maxDrain is how much of an unread response body is discarded so that the connection can be reused.
+const maxDrain = 64 << 10
+
+// closeBody drains at most maxDrain bytes of body and closes it.
+// Larger bodies are abandoned along with their connection instead of being downloaded.
+func closeBody(body io.ReadCloser) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(body, maxDrain))
+	_ = body.Close()
+}
+
+// spoolBody copies r to a temporary file, returning it along with its size.
+// The caller must close and remove the file.
+func spoolBody(r io.Reader) (*os.File, int64, error) {
+	f, err := os.CreateTemp("", "wsj-dl-*")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	n, err := io.Copy(f, r)
+	if err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return nil, 0, err
+	}
+	return f, n, nil
+}
+
+type upstreamReader struct {
+	r io.Reader
+}
 
-		slog.Info("Loaded file", "filename", issue, "url", u.String())
-		storeLatest(issue)
+func (u upstreamReader) Read(p []byte) (int, error) {
+	n, err := u.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		err = fmt.Errorf("%w: %w", ErrUpstream, err)
+	}
+	return n, err
+}
+
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
 
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		_, _ = io.WriteString(w, "/"+issue.ShortPath()+"\n")
+// handleFetchError writes err with a status code matching the stage of Fetcher.Fetch that failed.
+func handleFetchError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidURL), errors.Is(err, ErrInvalidFilename):
+		handleHTTPError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrExists):
+		handleHTTPError(w, err.Error()+" (set force=true to replace it)", http.StatusConflict)
+	case errors.Is(err, ErrInvalidContent):
+		handleHTTPError(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, ErrUpstream):
+		handleHTTPError(w, err.Error(), http.StatusBadGateway)
+	default:
+		handleHTTPError(w, err.Error(), http.StatusInternalServerError)
 	}
 }
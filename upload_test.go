@@ -1,11 +1,16 @@
 package main
 
 import (
+	"bytes"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -36,6 +41,12 @@ func (f *fakeS3) put(key string) {
 	f.keys = append(f.keys, key)
 }
 
+func (f *fakeS3) has(key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Contains(f.keys, key)
+}
+
 func (f *fakeS3) Keys() []string {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -49,25 +60,21 @@ func (f *fakeS3) Keys() []string {
 func newUpload(t *testing.T) (http.HandlerFunc, *fakeS3, string) {
 	t.Helper()
 
-	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		switch {
-		case r.URL.Path == paperPath:
-			http.Redirect(w, r, "/files/a1b2-issue-8-5-2026.pdf", http.StatusFound)
-		case strings.HasPrefix(r.URL.Path, "/files/"):
-			_, _ = w.Write([]byte("%PDF-1.4 fake"))
-		default:
-			http.Error(w, "not found", http.StatusNotFound)
-		}
-	}))
-	t.Cleanup(upstream.Close)
+	upstream := newUpstream(t)
 
 	keys := &fakeS3{}
 	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPut {
-			// Path is /<bucket>/<key>.
-			_, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		// Path is /<bucket>/<key>.
+		_, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		switch r.Method {
+		case http.MethodPut:
 			keys.put(key)
 			w.Header().Set("ETag", `"abc123"`)
+		case http.MethodHead:
+			if !keys.has(key) {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
 		}
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -82,13 +89,41 @@ func newUpload(t *testing.T) (http.HandlerFunc, *fakeS3, string) {
 	})
 	require.NoError(t, err)
 
-	conf := &Config{
-		S3Bucket:        "test-bucket",
-		UploadAuthKey:   authKey,
-		UploadUserAgent: "test-agent",
+	conf := newTestConfig()
+	fetcher := NewFetcher(conf, NewS3Storage(client, "test-bucket"))
+	return uploadHandler(conf, fetcher, NewJobQueue(conf, fetcher)), keys, upstream
+}
+
+// newTestConfig returns a Config that allows uploads from httptest servers on loopback.
+func newTestConfig() *Config {
+	return &Config{
+		UploadAuthKey:      authKey,
+		UploadUserAgent:    "test-agent",
+		UploadAllowPrivate: true,
+		UploadMaxRedirects: 5,
 	}
+}
+
+// newUpstream starts a fake upstream that redirects /todaysPaper to a dated filename and
+// returns its base URL.
+func newUpstream(t *testing.T) string {
+	t.Helper()
 
-	return uploadHandler(conf, client), keys, upstream.URL
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == paperPath:
+			http.Redirect(w, r, "/files/a1b2-issue-8-5-2026.pdf", http.StatusFound)
+		case strings.HasPrefix(r.URL.Path, "/files/"):
+			_, _ = w.Write([]byte("%PDF-1.4 fake"))
+		case strings.HasPrefix(r.URL.Path, "/html/"):
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write([]byte("<html>Please log in</html>"))
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream.URL
 }
 
 func TestUploadHandler(t *testing.T) {
@@ -144,6 +179,10 @@ func TestUploadHandler(t *testing.T) {
 			name: "upstream error", auth: authKey, path: "/gone",
 			wantCode: http.StatusBadGateway,
 		},
+		{
+			name: "not a pdf", auth: authKey, path: "/html/a1b2-issue-8-5-2026.pdf",
+			wantCode: http.StatusUnprocessableEntity,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -209,3 +248,182 @@ func TestStoreLatest(t *testing.T) {
 	storeLatest(older)
 	assert.Equal(t, newer, latest.Load(), "should not regress to an older issue")
 }
+
+func TestUploadHandler_conflict(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+
+	upstream := newUpstream(t)
+	store := newMemStorage()
+	store.add(issueKey, "%PDF-1.4 original")
+
+	conf := newTestConfig()
+	conf.UploadKeepBackups = true
+	fetcher := NewFetcher(conf, store)
+	handler := uploadHandler(conf, fetcher, NewJobQueue(conf, fetcher))
+
+	upload := func(force string) *httptest.ResponseRecorder {
+		q := url.Values{"url": {upstream + paperPath}}
+		if force != "" {
+			q.Set("force", force)
+		}
+		r := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/api/upload?"+q.Encode(), nil)
+		r.Header.Set("Authorization", authKey)
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	w := upload("")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, []string{issueKey}, store.Keys())
+
+	w = upload("false")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = upload("true")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, issueBody, w.Body.String())
+
+	keys := store.Keys()
+	require.Len(t, keys, 2)
+	assert.Equal(t, issueKey, keys[0])
+	assert.Regexp(t, `^backups/2026/08/05\.\d{8}T\d{6}Z\.pdf$`, keys[1])
+
+	backup, err := store.Get(t.Context(), keys[1])
+	require.NoError(t, err)
+	b, err := io.ReadAll(backup)
+	require.NoError(t, err)
+	assert.Equal(t, "%PDF-1.4 original", string(b))
+}
+
+func TestFetcher_Fetch_existingDate(t *testing.T) {
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		_, _ = w.Write([]byte("%PDF-1.4 fake"))
+	}))
+	t.Cleanup(upstream.Close)
+
+	store := newMemStorage()
+	store.add(issueKey, "%PDF-1.4 original")
+	fetcher := NewFetcher(newTestConfig(), store)
+
+	u, err := url.Parse(upstream.URL + "/issue.pdf")
+	require.NoError(t, err)
+	date := time.Date(2026, 8, 5, 0, 0, 0, 0, time.UTC)
+	issue, err := fetcher.Fetch(t.Context(), u, FetchOptions{Date: date})
+	require.ErrorIs(t, err, ErrExists)
+	assert.Equal(t, issueKey, issue.FullPath())
+	assert.Zero(t, hits.Load(), "upstream is not contacted for a stored issue")
+}
+
+func TestUploadHandler_file(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		date     string
+		body     string
+		wantCode int
+		wantBody string
+		wantKeys []string
+	}{
+		{
+			name: "short path filename", filename: "2026-08-05.pdf", body: "%PDF-1.4 fake",
+			wantCode: http.StatusOK, wantBody: issueBody, wantKeys: []string{issueKey},
+		},
+		{
+			name: "upstream filename", filename: "a1b2-issue-8-5-2026.pdf", body: "%PDF-1.4 fake",
+			wantCode: http.StatusOK, wantBody: issueBody, wantKeys: []string{issueKey},
+		},
+		{
+			name: "date param", filename: "paper.pdf", date: "2026-03-01", body: "%PDF-1.4 fake",
+			wantCode: http.StatusOK, wantBody: "/2026-03-01.pdf\n", wantKeys: []string{"2026/03/01.pdf"},
+		},
+		{
+			name: "unparseable filename", filename: "paper.pdf", body: "%PDF-1.4 fake",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "not a pdf", filename: "2026-08-05.pdf", body: "<html></html>",
+			wantCode: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(func() { latest.Store(nil) })
+
+			store := newMemStorage()
+			conf := newTestConfig()
+			fetcher := NewFetcher(conf, store)
+			handler := uploadHandler(conf, fetcher, NewJobQueue(conf, fetcher))
+
+			var buf bytes.Buffer
+			mw := multipart.NewWriter(&buf)
+			if tt.date != "" {
+				require.NoError(t, mw.WriteField("date", tt.date))
+			}
+			part, err := mw.CreateFormFile("file", tt.filename)
+			require.NoError(t, err)
+			_, err = io.WriteString(part, tt.body)
+			require.NoError(t, err)
+			require.NoError(t, mw.Close())
+
+			r := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/api/upload", &buf)
+			r.Header.Set("Authorization", authKey)
+			r.Header.Set("Content-Type", mw.FormDataContentType())
+			w := httptest.NewRecorder()
+
+			handler(w, r)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			if tt.wantBody != "" {
+				assert.Equal(t, tt.wantBody, w.Body.String())
+			}
+			assert.Equal(t, tt.wantKeys, store.Keys())
+		})
+	}
+}
+
+func TestUploadHandler_slowBody(t *testing.T) {
+	t.Cleanup(func() { latest.Store(nil) })
+
+	store := newMemStorage()
+	conf := newTestConfig()
+	conf.ReadHeaderTimeout = 50 * time.Millisecond
+	fetcher := NewFetcher(conf, store)
+
+	srv := httptest.NewUnstartedServer(nil)
+	srv.Config = newServer(conf, uploadHandler(conf, fetcher, NewJobQueue(conf, fetcher)))
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	// Send the file in chunks over several times the header timeout.
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		part, err := mw.CreateFormFile("file", "2026-08-05.pdf")
+		if err == nil {
+			for _, chunk := range []string{"%PDF-1.4", " slow", " upload"} {
+				time.Sleep(50 * time.Millisecond)
+				if _, err = io.WriteString(part, chunk); err != nil {
+					break
+				}
+			}
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		_ = pw.CloseWithError(err)
+	}()
+
+	r, err := http.NewRequestWithContext(t.Context(), http.MethodPost, srv.URL+"/api/upload", pr)
+	require.NoError(t, err)
+	r.Header.Set("Authorization", authKey)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	res, err := srv.Client().Do(r)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = res.Body.Close() })
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, []string{issueKey}, store.Keys())
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"slices"
+	"strings"
+)
+
+var ErrInvalidContent = errors.New("invalid content")
+
+const (
+	pdfMagic   = "%PDF-"
+	pdfTrailer = "%%EOF"
+	// pdfTrailerWindow is how far from the end of the file the trailer is searched for.
+	// Some generators append whitespace or garbage after it.
+	pdfTrailerWindow = 1024
+)
+
+// checkContentType returns an error if contentType is not in conf.UploadContentTypes.
+// An empty content type or allowlist is always accepted.
+func checkContentType(conf *Config, contentType string) error {
+	if contentType == "" || len(conf.UploadContentTypes) == 0 {
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidContent, err)
+	}
+
+	if !slices.ContainsFunc(conf.UploadContentTypes, func(v string) bool {
+		return strings.EqualFold(v, mediaType)
+	}) {
+		return fmt.Errorf("%w: content type %q is not allowed", ErrInvalidContent, mediaType)
+	}
+	return nil
+}
+
+// checkSize returns an error if size is outside of the configured bounds.
+func checkSize(conf *Config, size int64) error {
+	switch {
+	case size < conf.UploadMinSize:
+		return fmt.Errorf("%w: %d bytes is smaller than the minimum of %d", ErrInvalidContent, size, conf.UploadMinSize)
+	case conf.UploadMaxSize > 0 && size > conf.UploadMaxSize:
+		return fmt.Errorf("%w: larger than the maximum of %d bytes", ErrInvalidContent, conf.UploadMaxSize)
+	}
+	return nil
+}
+
+// validateContent checks a downloaded issue before it is stored.
+//
+// PDFs must start with the PDF header, and if conf.UploadCheckTrailer is set,
+// must end with an EOF marker.
+func validateContent(conf *Config, issue *Issue, r io.ReaderAt, size int64) error {
+	if err := checkSize(conf, size); err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(issue.Ext, ".pdf") {
+		return nil
+	}
+
+	buf := make([]byte, len(pdfMagic))
+	if _, err := r.ReadAt(buf, 0); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if string(buf) != pdfMagic {
+		return fmt.Errorf("%w: missing PDF header", ErrInvalidContent)
+	}
+
+	if conf.UploadCheckTrailer {
+		off := max(size-pdfTrailerWindow, 0)
+		buf := make([]byte, size-off)
+		if _, err := r.ReadAt(buf, off); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if !bytes.Contains(buf, []byte(pdfTrailer)) {
+			return fmt.Errorf("%w: missing PDF trailer", ErrInvalidContent)
+		}
+	}
+	return nil
+}
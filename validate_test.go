@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/caarlos0/env/v11"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckContentType(t *testing.T) {
+	// The defaults allow every format in the default LATEST_FORMATS.
+	conf, err := env.ParseAsWithOptions[Config](env.Options{
+		Environment: map[string]string{"UPLOAD_AUTH_KEY": authKey},
+	})
+	require.NoError(t, err)
+	tests := []struct {
+		name        string
+		contentType string
+		wantErr     require.ErrorAssertionFunc
+	}{
+		{"pdf", "application/pdf", require.NoError},
+		{"params", "Application/PDF; qs=1", require.NoError},
+		{"epub", "application/epub+zip", require.NoError},
+		{"octet-stream", "application/octet-stream", require.NoError},
+		{"missing", "", require.NoError},
+		{"html", "text/html; charset=utf-8", require.Error},
+		{"invalid", "/", require.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.wantErr(t, checkContentType(&conf, tt.contentType))
+		})
+	}
+
+	require.NoError(t, checkContentType(&Config{}, "text/html"), "empty allowlist accepts anything")
+}
+
+func TestValidateContent(t *testing.T) {
+	const pdf = "%PDF-1.4\n1 0 obj\n%%EOF\n"
+	tests := []struct {
+		name    string
+		conf    Config
+		ext     string
+		body    string
+		wantErr require.ErrorAssertionFunc
+	}{
+		{"pdf", Config{}, ".pdf", pdf, require.NoError},
+		{"html", Config{}, ".pdf", "<html>Please log in</html>", require.Error},
+		{"empty", Config{}, ".pdf", "", require.Error},
+		{"other ext", Config{}, ".epub", "PK\x03\x04", require.NoError},
+		{"trailer", Config{UploadCheckTrailer: true}, ".pdf", pdf, require.NoError},
+		{"trailer padded", Config{UploadCheckTrailer: true}, ".pdf", pdf + strings.Repeat(" ", 512), require.NoError},
+		{"truncated", Config{UploadCheckTrailer: true}, ".pdf", "%PDF-1.4\n1 0 obj\n", require.Error},
+		{"too small", Config{UploadMinSize: 1024}, ".pdf", pdf, require.Error},
+		{"too large", Config{UploadMaxSize: 8}, ".pdf", pdf, require.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issue := NewIssueFromDate(date, tt.ext)
+			err := validateContent(&tt.conf, issue, strings.NewReader(tt.body), int64(len(tt.body)))
+			tt.wantErr(t, err)
+			if err != nil {
+				require.ErrorIs(t, err, ErrInvalidContent)
+			}
+		})
+	}
+}